@@ -0,0 +1,117 @@
+// aead.go
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// Với nonce 96 bit ngẫu nhiên, một phiên bản khóa chỉ được mã hóa tối đa 2^32 thông điệp
+// (NIST SP 800-38D) để xác suất trùng nonce không đáng kể; sau đó phải xoay vòng khóa.
+// XChaCha20-Poly1305 dùng nonce 192 bit nên không bị giới hạn này.
+const aeadMessageLimit = 1 << 32
+
+// Bộ đếm được dành trước và ghi xuống keystore theo từng khối để không phải ghi mỗi lần mã hóa
+const aeadCounterBlock = 1 << 16
+
+// Lỗi khi phiên bản khóa đã dùng hết số lần mã hóa cho phép
+var errAEADLimitReached = errors.New("phiên bản khóa đã mã hóa đủ 2^32 thông điệp với nonce 96 bit, cần xoay vòng khóa")
+
+// Độ dài hợp lệ của khóa AES theo bit
+var aesKeySizes = map[int]bool{128: true, 192: true, 256: true}
+
+// Hàm sinh khóa bí mật bits bit
+func generateSecretKey(bits int) ([]byte, error) {
+	key := make([]byte, bits/8)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// Tạo AEAD cho thuật toán với khóa bí mật: AES-GCM, ChaCha20-Poly1305 (nonce 96 bit) hoặc XChaCha20-Poly1305 (nonce 192 bit)
+func newAEAD(algorithm string, key []byte) (cipher.AEAD, error) {
+	switch algorithm {
+	case "AES-GCM":
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		return cipher.NewGCM(block)
+	case "CHACHA20-POLY1305":
+		return chacha20poly1305.New(key)
+	case "XCHACHA20-POLY1305":
+		return chacha20poly1305.NewX(key)
+	}
+	return nil, fmt.Errorf("thuật toán không được hỗ trợ: %s", algorithm)
+}
+
+// Đếm một lần mã hóa bằng phiên bản kv trước khi mã hóa, từ chối khi đã đạt aeadMessageLimit.
+// Bộ đếm chỉ tăng nên sau khi khởi động lại không bao giờ thấp hơn số lần đã mã hóa thật.
+func (reg *KeyRegistry) countAEADEncryption(key *Key, kv *KeyVersion, algorithm string) error {
+	if algorithm == "XCHACHA20-POLY1305" {
+		return nil
+	}
+
+	key.mu.Lock()
+	if kv.encryptions >= aeadMessageLimit {
+		key.mu.Unlock()
+		return errAEADLimitReached
+	}
+	kv.encryptions++
+	save := kv.encryptions > kv.encryptionsSaved
+	if save {
+		kv.encryptionsSaved = min(kv.encryptions-1+aeadCounterBlock, aeadMessageLimit)
+	}
+	key.mu.Unlock()
+
+	if !save {
+		return nil
+	}
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	return reg.saveKey(key)
+}
+
+// Mã hóa và xác thực thông điệp cùng dữ liệu liên kết associatedData (không được mã hóa).
+// Nonce ngẫu nhiên do server sinh cho từng thông điệp; bản mã: base64(nonce || ciphertext || tag)
+func encryptAEAD(algorithm string, key []byte, message, associatedData string) (string, error) {
+	aead, err := newAEAD(algorithm, key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(message)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	out := aead.Seal(nonce, nonce, []byte(message), []byte(associatedData))
+	return base64.StdEncoding.EncodeToString(out), nil
+}
+
+// Giải mã và kiểm tra thông điệp, associatedData phải trùng với lúc mã hóa
+func decryptAEAD(algorithm string, key []byte, encryptedMessage, associatedData string) (string, error) {
+	aead, err := newAEAD(algorithm, key)
+	if err != nil {
+		return "", err
+	}
+	data, err := base64.StdEncoding.DecodeString(encryptedMessage)
+	if err != nil {
+		return "", errors.New("sai định dạng bản mã")
+	}
+	if len(data) < aead.NonceSize()+aead.Overhead() {
+		return "", errors.New("bản mã quá ngắn")
+	}
+	nonce, ciphertext := data[:aead.NonceSize()], data[aead.NonceSize():]
+
+	plaintext, err := aead.Open(nil, nonce, ciphertext, []byte(associatedData))
+	if err != nil {
+		return "", errors.New("bản mã hoặc dữ liệu liên kết đã bị thay đổi, hoặc sai khóa")
+	}
+	return string(plaintext), nil
+}
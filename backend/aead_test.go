@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+var aeadAlgorithms = []string{"AES-GCM", "CHACHA20-POLY1305", "XCHACHA20-POLY1305"}
+
+func TestAEADRoundTrip(t *testing.T) {
+	for _, algorithm := range aeadAlgorithms {
+		key, _ := generateSecretKey(256)
+		for _, ad := range []string{"", "người nhận: an"} {
+			for _, message := range []string{"", "Xin chào AEAD!", strings.Repeat("x", 1000)} {
+				ciphertext, err := encryptAEAD(algorithm, key, message, ad)
+				if err != nil {
+					t.Fatalf("%s: %v", algorithm, err)
+				}
+				plaintext, err := decryptAEAD(algorithm, key, ciphertext, ad)
+				if err != nil || plaintext != message {
+					t.Fatalf("%s: giải mã %q, %v", algorithm, plaintext, err)
+				}
+			}
+		}
+
+		// Mỗi lần mã hóa dùng nonce mới
+		c1, _ := encryptAEAD(algorithm, key, "m", "")
+		c2, _ := encryptAEAD(algorithm, key, "m", "")
+		if c1 == c2 {
+			t.Fatalf("%s: hai bản mã của cùng thông điệp trùng nhau", algorithm)
+		}
+	}
+	if _, err := encryptAEAD("AES-CBC", make([]byte, 32), "m", ""); err == nil {
+		t.Fatal("chấp nhận thuật toán không được hỗ trợ")
+	}
+}
+
+func TestAEADRejectsTampering(t *testing.T) {
+	for _, algorithm := range aeadAlgorithms {
+		key, _ := generateSecretKey(256)
+		ciphertext, err := encryptAEAD(algorithm, key, "Xin chào AEAD!", "người nhận: an")
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := base64.StdEncoding.DecodeString(ciphertext)
+
+		if _, err := decryptAEAD(algorithm, key, ciphertext, "người nhận: bình"); err == nil {
+			t.Errorf("%s: giải mã được với dữ liệu liên kết khác", algorithm)
+		}
+		if _, err := decryptAEAD(algorithm, key, ciphertext, ""); err == nil {
+			t.Errorf("%s: giải mã được khi thiếu dữ liệu liên kết", algorithm)
+		}
+
+		// Sửa một byte ở nonce, phần mã hóa và tag
+		for _, i := range []int{0, len(data) - 17, len(data) - 1} {
+			tampered := append([]byte{}, data...)
+			tampered[i] ^= 0x01
+			if _, err := decryptAEAD(algorithm, key, base64.StdEncoding.EncodeToString(tampered), "người nhận: an"); err == nil {
+				t.Errorf("%s: giải mã được bản mã bị sửa ở byte %d", algorithm, i)
+			}
+		}
+
+		// Bản mã bị cắt: mất một byte tag, chỉ còn nonce || tag hoặc ngắn hơn nonce
+		for _, n := range []int{len(data) - 1, len(data) - len("Xin chào AEAD!"), 10} {
+			if _, err := decryptAEAD(algorithm, key, base64.StdEncoding.EncodeToString(data[:n]), "người nhận: an"); err == nil {
+				t.Errorf("%s: giải mã được bản mã bị cắt còn %d byte", algorithm, n)
+			}
+		}
+		if _, err := decryptAEAD(algorithm, key, "!!!", ""); err == nil {
+			t.Errorf("%s: giải mã được bản mã không phải base64", algorithm)
+		}
+
+		other, _ := generateSecretKey(256)
+		if _, err := decryptAEAD(algorithm, other, ciphertext, "người nhận: an"); err == nil {
+			t.Errorf("%s: giải mã được bằng khóa khác", algorithm)
+		}
+	}
+}
+
+func TestAEADRejectsWrongKeyVersion(t *testing.T) {
+	reg := useTestRegistry(t)
+	if _, err := reg.generate(KeyTypeChaCha20, "chacha", KeyOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	encrypt := EncryptRequest{Algorithm: "CHACHA20-POLY1305", KeyID: "chacha", Message: "bản tin", AssociatedData: "ad"}
+	var enc EncryptResponse
+	if code := serveJSON(t, "/encrypt", encryptHandler, http.MethodPost, "/encrypt", encrypt, &enc); code != http.StatusOK {
+		t.Fatalf("mã hóa: %d", code)
+	}
+	if code := serveJSON(t, "/keys/{id}/rotate", rotateKeyHandler, http.MethodPost, "/keys/chacha/rotate", nil, nil); code != http.StatusOK {
+		t.Fatalf("xoay vòng: %d", code)
+	}
+
+	// Bản mã v1 gắn nhãn v2 hoặc phiên bản không tồn tại không giải mã được
+	body := strings.TrimPrefix(enc.EncryptedMessage, "v1:")
+	for ciphertext, want := range map[string]int{
+		"v2:" + body: http.StatusInternalServerError,
+		"v9:" + body: http.StatusNotFound,
+	} {
+		req := DecryptRequest{Algorithm: "CHACHA20-POLY1305", KeyID: "chacha", EncryptedMessage: ciphertext, AssociatedData: "ad"}
+		if code := serveJSON(t, "/decrypt", decryptHandler, http.MethodPost, "/decrypt", req, nil); code != want {
+			t.Errorf("giải mã %q: %d, cần %d", ciphertext[:3], code, want)
+		}
+	}
+	var dec DecryptResponse
+	req := DecryptRequest{Algorithm: "CHACHA20-POLY1305", KeyID: "chacha", EncryptedMessage: enc.EncryptedMessage, AssociatedData: "ad"}
+	if code := serveJSON(t, "/decrypt", decryptHandler, http.MethodPost, "/decrypt", req, &dec); code != http.StatusOK || dec.DecryptedMessage != "bản tin" {
+		t.Fatalf("giải mã bản mã v1 sau xoay vòng: %d %q", code, dec.DecryptedMessage)
+	}
+}
+
+func TestAEADMessageLimitForcesRotation(t *testing.T) {
+	reg := useTestRegistry(t)
+	if _, err := reg.generate(KeyTypeAES, "aes", KeyOptions{Bits: 256}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reg.generate(KeyTypeChaCha20, "chacha", KeyOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	encrypt := func(algorithm, id string) int {
+		req := EncryptRequest{Algorithm: algorithm, KeyID: id, Message: "bản tin"}
+		return serveJSON(t, "/encrypt", encryptHandler, http.MethodPost, "/encrypt", req, nil)
+	}
+
+	// Bộ đếm được ghi xuống keystore và không giảm sau khi đọc lại
+	for range 3 {
+		if code := encrypt("AES-GCM", "aes"); code != http.StatusOK {
+			t.Fatalf("mã hóa: %d", code)
+		}
+	}
+	key, _ := reg.get("aes")
+	if kv := key.latest(); kv.encryptions != 3 || kv.encryptionsSaved < 3 {
+		t.Fatalf("bộ đếm %d, đã ghi %d", kv.encryptions, kv.encryptionsSaved)
+	}
+	reloaded, err := reg.loadKey("aes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := reloaded.latest().encryptions; n < 3 {
+		t.Fatalf("bộ đếm sau khi đọc lại: %d", n)
+	}
+
+	// Đạt giới hạn 2^32 thì phải xoay vòng khóa
+	for _, id := range []string{"aes", "chacha"} {
+		key, _ := reg.get(id)
+		key.latest().encryptions = aeadMessageLimit
+	}
+	if code := encrypt("AES-GCM", "aes"); code != http.StatusConflict {
+		t.Fatalf("AES-GCM quá giới hạn: %d", code)
+	}
+	if code := encrypt("CHACHA20-POLY1305", "chacha"); code != http.StatusConflict {
+		t.Fatalf("ChaCha20-Poly1305 quá giới hạn: %d", code)
+	}
+	// Nonce 192 bit của XChaCha20-Poly1305 không bị giới hạn
+	if code := encrypt("XCHACHA20-POLY1305", "chacha"); code != http.StatusOK {
+		t.Fatalf("XChaCha20-Poly1305: %d", code)
+	}
+
+	if code := serveJSON(t, "/keys/{id}/rotate", rotateKeyHandler, http.MethodPost, "/keys/aes/rotate", nil, nil); code != http.StatusOK {
+		t.Fatalf("xoay vòng: %d", code)
+	}
+	if code := encrypt("AES-GCM", "aes"); code != http.StatusOK {
+		t.Fatalf("mã hóa sau xoay vòng: %d", code)
+	}
+}
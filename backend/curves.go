@@ -0,0 +1,480 @@
+// curves.go
+package main
+
+import (
+	"crypto/elliptic"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// Đường cong Weierstrass y^2 = x^3 + ax + b trên F_p với điểm sinh G có bậc N, cofactor H.
+// Dùng cho các phép toán tự cài đặt trong ecc.go (EC-ElGamal, ECDSA sách giáo khoa).
+type Curve struct {
+	Name   string
+	P      *big.Int
+	A, B   *big.Int
+	Gx, Gy *big.Int
+	N, H   *big.Int
+}
+
+// Tham số đường cong dạng hex, dùng trong JSON
+type CurveParams struct {
+	Name string `json:"name,omitempty"`
+	P    string `json:"p"`
+	A    string `json:"a"`
+	B    string `json:"b"`
+	Gx   string `json:"gx"`
+	Gy   string `json:"gy"`
+	N    string `json:"n"`
+	H    string `json:"h,omitempty"`
+}
+
+// Các đường cong có sẵn cho EC-ElGamal (mặc định P-256)
+var ecCurves = map[string]*Curve{}
+
+func init() {
+	for _, c := range []elliptic.Curve{elliptic.P256(), elliptic.P384(), elliptic.P521()} {
+		params := c.Params()
+		ecCurves[params.Name] = &Curve{
+			Name: params.Name,
+			P:    params.P,
+			A:    new(big.Int).Sub(params.P, big.NewInt(3)), // a = -3
+			B:    params.B,
+			Gx:   params.Gx,
+			Gy:   params.Gy,
+			N:    params.N,
+			H:    big.NewInt(1),
+		}
+	}
+
+	values, err := parseHexInts(
+		"fffffffffffffffffffffffffffffffffffffffffffffffffffffffefffffc2f",
+		"79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798",
+		"483ada7726a3c4655da4fbfc0e1108a8fd17b448a68554199c47d08ffb10d4b8",
+		"fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364141",
+	)
+	if err != nil {
+		panic(err)
+	}
+	ecCurves["secp256k1"] = &Curve{
+		Name: "secp256k1",
+		P:    values[0],
+		A:    big.NewInt(0),
+		B:    big.NewInt(7),
+		Gx:   values[1],
+		Gy:   values[2],
+		N:    values[3],
+		H:    big.NewInt(1),
+	}
+}
+
+// Tham số của đường cong dạng hex
+func (c *Curve) params() CurveParams {
+	return CurveParams{
+		Name: c.Name,
+		P:    c.P.Text(16),
+		A:    c.A.Text(16),
+		B:    c.B.Text(16),
+		Gx:   c.Gx.Text(16),
+		Gy:   c.Gy.Text(16),
+		N:    c.N.Text(16),
+		H:    c.H.Text(16),
+	}
+}
+
+// Đọc tham số đường cong dạng hex (cofactor mặc định là 1)
+func (cp *CurveParams) curve() (*Curve, error) {
+	h := cp.H
+	if h == "" {
+		h = "1"
+	}
+	values, err := parseHexInts(cp.P, cp.A, cp.B, cp.Gx, cp.Gy, cp.N, h)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range values {
+		if v.BitLen() > curveMaxBits+1 {
+			return nil, fmt.Errorf("tham số đường cong dài quá %d bit", curveMaxBits+1)
+		}
+	}
+	name := cp.Name
+	if name == "" {
+		name = "custom"
+	}
+	c := &Curve{Name: name, P: values[0], Gx: values[3], Gy: values[4], N: values[5], H: values[6]}
+	c.A = new(big.Int).Mod(values[1], c.P)
+	c.B = new(big.Int).Mod(values[2], c.P)
+	return c, nil
+}
+
+// Hai đường cong có cùng tham số (không so sánh tên)
+func (c *Curve) equal(o *Curve) bool {
+	return c.P.Cmp(o.P) == 0 && c.A.Cmp(o.A) == 0 && c.B.Cmp(o.B) == 0 &&
+		c.Gx.Cmp(o.Gx) == 0 && c.Gy.Cmp(o.Gy) == 0 && c.N.Cmp(o.N) == 0 && c.H.Cmp(o.H) == 0
+}
+
+// Kiểm tra điểm (x, y) nằm trên đường cong; điểm vô cực (nil) luôn hợp lệ
+func (c *Curve) isOnCurve(x, y *big.Int) bool {
+	if x == nil || y == nil {
+		return x == nil && y == nil
+	}
+	if x.Sign() < 0 || x.Cmp(c.P) >= 0 || y.Sign() < 0 || y.Cmp(c.P) >= 0 {
+		return false
+	}
+	lhs := new(big.Int).Mul(y, y)
+	lhs.Mod(lhs, c.P)
+	return lhs.Cmp(c.rhs(x)) == 0
+}
+
+// Vế phải x^3 + ax + b mod p
+func (c *Curve) rhs(x *big.Int) *big.Int {
+	r := new(big.Int).Exp(x, big.NewInt(3), c.P)
+	r.Add(r, new(big.Int).Mul(c.A, x))
+	r.Add(r, c.B)
+	return r.Mod(r, c.P)
+}
+
+// Biệt thức 4a^3 + 27b^2 mod p, đường cong suy biến khi bằng 0
+func (c *Curve) discriminant() *big.Int {
+	disc := new(big.Int).Mul(big.NewInt(4), new(big.Int).Exp(c.A, big.NewInt(3), c.P))
+	disc.Add(disc, new(big.Int).Mul(big.NewInt(27), new(big.Int).Mul(c.B, c.B)))
+	return disc.Mod(disc, c.P)
+}
+
+// Kết quả một bước kiểm tra đường cong: "ok", "warn" (yếu về bảo mật) hoặc "fail" (không hợp lệ)
+type CurveCheck struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// Báo cáo kiểm tra đường cong
+type CurveReport struct {
+	Valid           bool         `json:"valid"`
+	Secure          bool         `json:"secure"`
+	Order           string       `json:"order,omitempty"`
+	EmbeddingDegree int          `json:"embeddingDegree,omitempty"`
+	Checks          []CurveCheck `json:"checks"`
+}
+
+// Độ dài bit lớn nhất của p (bằng P-521), giới hạn chi phí kiểm tra đường cong do người dùng gửi lên
+const curveMaxBits = 521
+
+// Đường cong có p không lớn hơn giá trị này được đếm điểm trực tiếp
+const smallCurveMaxP = 1 << 20
+
+// Bậc nhúng lớn nhất được kiểm tra cho tấn công MOV / Frey-Rück
+const movMaxDegree = 20
+
+func (r *CurveReport) add(name, status, detail string, args ...any) {
+	r.Checks = append(r.Checks, CurveCheck{Name: name, Status: status, Detail: fmt.Sprintf(detail, args...)})
+	if status == "fail" {
+		r.Valid = false
+	}
+	if status != "ok" {
+		r.Secure = false
+	}
+}
+
+// Lỗi đầu tiên trong báo cáo, nil nếu đường cong hợp lệ
+func (r *CurveReport) err() error {
+	for _, check := range r.Checks {
+		if check.Status == "fail" {
+			return fmt.Errorf("%s: %s", check.Name, check.Detail)
+		}
+	}
+	return nil
+}
+
+// Kiểm tra tham số đường cong: p nguyên tố, biệt thức khác 0, G nằm trên đường cong,
+// n nguyên tố và n*G = O, cận Hasse và cofactor, tấn công MOV và đường cong dị thường
+func checkCurve(c *Curve) *CurveReport {
+	report := &CurveReport{Valid: true, Secure: true}
+	one := big.NewInt(1)
+
+	// Giới hạn kích thước trước mọi phép tính: theo cận Hasse n và h*n dài hơn p nhiều nhất 1 bit
+	if c.P.BitLen() > curveMaxBits {
+		report.add("prime-field", "fail", "p dài %d bit, tối đa %d bit", c.P.BitLen(), curveMaxBits)
+		return report
+	}
+	if c.N.BitLen() > c.P.BitLen()+1 || c.H.BitLen() > c.P.BitLen()+1 {
+		report.add("generator-order", "fail", "n hoặc h lớn hơn số điểm tối đa của đường cong")
+		return report
+	}
+
+	if c.P.Cmp(big.NewInt(3)) <= 0 || !c.P.ProbablyPrime(20) {
+		report.add("prime-field", "fail", "p phải là số nguyên tố lớn hơn 3")
+		return report
+	}
+	report.add("prime-field", "ok", "p là số nguyên tố %d bit", c.P.BitLen())
+
+	disc := c.discriminant()
+	if disc.Sign() == 0 {
+		report.add("non-singular", "fail", "4a^3 + 27b^2 = 0 (mod p), đường cong suy biến")
+		return report
+	}
+	report.add("non-singular", "ok", "4a^3 + 27b^2 = %s (mod p)", disc.Text(16))
+
+	if !c.isOnCurve(c.Gx, c.Gy) {
+		report.add("generator-on-curve", "fail", "điểm sinh G không nằm trên đường cong")
+		return report
+	}
+	report.add("generator-on-curve", "ok", "G nằm trên đường cong")
+
+	if c.N.Cmp(big.NewInt(2)) < 0 || !c.N.ProbablyPrime(20) {
+		report.add("generator-order", "fail", "bậc n của G phải là số nguyên tố")
+		return report
+	}
+	if x, _ := pointMultiply(c, c.N, c.Gx, c.Gy); x != nil {
+		report.add("generator-order", "fail", "n*G khác điểm vô cực")
+		return report
+	}
+	report.add("generator-order", "ok", "n nguyên tố và n*G = O")
+
+	// Cận Hasse: |p + 1 - h*n| <= 2*sqrt(p), tức là (p + 1 - h*n)^2 <= 4p
+	if c.H.Sign() <= 0 {
+		report.add("cofactor", "fail", "cofactor h phải dương")
+		return report
+	}
+	order := new(big.Int).Mul(c.H, c.N)
+	trace := new(big.Int).Sub(new(big.Int).Add(c.P, one), order)
+	if new(big.Int).Mul(trace, trace).Cmp(new(big.Int).Lsh(c.P, 2)) > 0 {
+		report.add("hasse-bound", "fail", "h*n nằm ngoài cận Hasse [p+1-2√p, p+1+2√p]")
+		return report
+	}
+	report.add("hasse-bound", "ok", "h*n nằm trong cận Hasse, vết t = %s", trace.String())
+
+	// Cofactor: đếm điểm trực tiếp với đường cong nhỏ, hoặc suy ra từ cận Hasse khi n > 4√p
+	switch {
+	case c.P.Cmp(big.NewInt(smallCurveMaxP)) <= 0:
+		count := countCurvePoints(c)
+		if count.Cmp(order) != 0 {
+			report.add("cofactor", "fail", "đường cong có %s điểm, khác h*n = %s", count, order)
+			return report
+		}
+		report.add("cofactor", "ok", "đếm được %s điểm = h*n", count)
+	case new(big.Int).Mul(c.N, c.N).Cmp(new(big.Int).Lsh(c.P, 4)) > 0:
+		report.add("cofactor", "ok", "n > 4√p nên h được xác định duy nhất bởi cận Hasse")
+	default:
+		report.add("cofactor", "warn", "n <= 4√p, không kiểm tra được h nếu không đếm điểm")
+	}
+	report.Order = order.Text(16)
+
+	// Đường cong dị thường (#E = p): ECDLP giải được trong thời gian đa thức (tấn công Smart)
+	if order.Cmp(c.P) == 0 {
+		report.add("anomalous", "warn", "#E = p, đường cong dị thường")
+	} else {
+		report.add("anomalous", "ok", "#E khác p")
+	}
+
+	// Bậc nhúng k: số nhỏ nhất để n | p^k - 1; k nhỏ thì ECDLP quy về DLP trên F_{p^k}
+	if c.N.Cmp(c.P) != 0 {
+		pk := new(big.Int).Mod(c.P, c.N)
+		q := new(big.Int).Set(pk)
+		for k := 1; k <= movMaxDegree; k++ {
+			if q.Cmp(one) == 0 {
+				report.EmbeddingDegree = k
+				break
+			}
+			q.Mul(q, pk).Mod(q, c.N)
+		}
+	}
+	if report.EmbeddingDegree > 0 {
+		report.add("mov", "warn", "bậc nhúng k = %d, dễ bị tấn công MOV/Frey-Rück", report.EmbeddingDegree)
+	} else {
+		report.add("mov", "ok", "bậc nhúng lớn hơn %d", movMaxDegree)
+	}
+
+	if c.N.BitLen() < 160 {
+		report.add("size", "warn", "n chỉ có %d bit, chỉ dùng để minh họa", c.N.BitLen())
+	} else {
+		report.add("size", "ok", "n có %d bit", c.N.BitLen())
+	}
+	return report
+}
+
+// Đếm số điểm (kể cả điểm vô cực) của đường cong nhỏ: #E = 1 + Σ_x số nghiệm y của y^2 = x^3 + ax + b
+func countCurvePoints(c *Curve) *big.Int {
+	p := c.P.Int64()
+	roots := squareRootCounts(p)
+	a, b := c.A.Int64(), c.B.Int64()
+
+	count := int64(1)
+	for x := int64(0); x < p; x++ {
+		count += int64(roots[(x*x%p*x+a*x+b)%p])
+	}
+	return big.NewInt(count)
+}
+
+// Số căn bậc hai modulo p của mỗi phần dư 0..p-1
+func squareRootCounts(p int64) []uint8 {
+	roots := make([]uint8, p)
+	for y := int64(0); y < p; y++ {
+		roots[y*y%p]++
+	}
+	return roots
+}
+
+// Kiểm tra tham số đường cong, trả về lỗi đầu tiên nếu đường cong không hợp lệ
+func validateCurve(c *Curve) error {
+	return checkCurve(c).err()
+}
+
+// Các đường cong do người dùng định nghĩa qua /curves, lưu trong curves.json
+var customCurves = struct {
+	mu     sync.RWMutex
+	path   string
+	curves map[string]*Curve
+}{curves: map[string]*Curve{}}
+
+// Nạp các đường cong tự định nghĩa từ thư mục keystore
+func loadCustomCurves(dir string) error {
+	customCurves.mu.Lock()
+	defer customCurves.mu.Unlock()
+
+	customCurves.path = filepath.Join(dir, "curves.json")
+	data, err := os.ReadFile(customCurves.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var list []CurveParams
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("curves.json không hợp lệ: %v", err)
+	}
+	for _, params := range list {
+		c, err := params.curve()
+		if err != nil {
+			return fmt.Errorf("đường cong %s: %v", params.Name, err)
+		}
+		if err := validateCurve(c); err != nil {
+			return fmt.Errorf("đường cong %s: %v", params.Name, err)
+		}
+		customCurves.curves[c.Name] = c
+	}
+	return nil
+}
+
+// Ghi các đường cong tự định nghĩa xuống curves.json (gọi khi đang giữ khóa)
+func saveCustomCurves() error {
+	list := []CurveParams{}
+	for _, c := range customCurves.curves {
+		list = append(list, c.params())
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(customCurves.path, data)
+}
+
+// Đăng ký đường cong tự định nghĩa với tên trong params
+func addCustomCurve(params CurveParams) (*Curve, *CurveReport, error) {
+	if !keyIDPattern.MatchString(params.Name) {
+		return nil, nil, fmt.Errorf("tên đường cong không hợp lệ: %q", params.Name)
+	}
+	if _, ok := ecCurves[params.Name]; ok {
+		return nil, nil, fmt.Errorf("đường cong %q đã có sẵn", params.Name)
+	}
+	c, err := params.curve()
+	if err != nil {
+		return nil, nil, err
+	}
+	report := checkCurve(c)
+	if err := report.err(); err != nil {
+		return nil, report, err
+	}
+
+	customCurves.mu.Lock()
+	defer customCurves.mu.Unlock()
+	if _, ok := customCurves.curves[c.Name]; ok {
+		return nil, report, fmt.Errorf("đường cong %q đã tồn tại", c.Name)
+	}
+	customCurves.curves[c.Name] = c
+	if err := saveCustomCurves(); err != nil {
+		delete(customCurves.curves, c.Name)
+		return nil, report, err
+	}
+	return c, report, nil
+}
+
+// Xóa đường cong tự định nghĩa; khóa đã sinh vẫn dùng được vì lưu kèm tham số đường cong
+func deleteCustomCurve(name string) error {
+	customCurves.mu.Lock()
+	defer customCurves.mu.Unlock()
+
+	c, ok := customCurves.curves[name]
+	if !ok {
+		return fmt.Errorf("không tìm thấy đường cong tự định nghĩa %q", name)
+	}
+	delete(customCurves.curves, name)
+	if err := saveCustomCurves(); err != nil {
+		customCurves.curves[name] = c
+		return err
+	}
+	return nil
+}
+
+// Tìm đường cong theo tên: đường cong có sẵn trước, sau đó đến đường cong tự định nghĩa
+func lookupCurve(name string) (*Curve, bool) {
+	if c, ok := ecCurves[name]; ok {
+		return c, true
+	}
+	customCurves.mu.RLock()
+	defer customCurves.mu.RUnlock()
+
+	c, ok := customCurves.curves[name]
+	return c, ok
+}
+
+// Tất cả đường cong, sắp xếp theo tên
+func listCurves() []*Curve {
+	customCurves.mu.RLock()
+	defer customCurves.mu.RUnlock()
+
+	var curves []*Curve
+	for _, c := range ecCurves {
+		curves = append(curves, c)
+	}
+	for _, c := range customCurves.curves {
+		curves = append(curves, c)
+	}
+	sort.Slice(curves, func(i, j int) bool { return curves[i].Name < curves[j].Name })
+	return curves
+}
+
+// Đường cong cho khóa mới: tham số tự chọn nếu có, nếu không thì theo tên (mặc định P-256)
+func resolveCurve(name string, params *CurveParams) (*Curve, error) {
+	if params != nil {
+		if _, ok := ecCurves[params.Name]; ok {
+			return nil, fmt.Errorf("tên đường cong %q trùng với đường cong có sẵn", params.Name)
+		}
+		c, err := params.curve()
+		if err != nil {
+			return nil, err
+		}
+		if err := validateCurve(c); err != nil {
+			return nil, err
+		}
+		return c, nil
+	}
+	if name == "" {
+		name = "P-256"
+	}
+	c, ok := lookupCurve(name)
+	if !ok {
+		return nil, fmt.Errorf("đường cong không được hỗ trợ: %s", name)
+	}
+	return c, nil
+}
@@ -0,0 +1,131 @@
+// curves_http.go
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Thông tin một đường cong
+type CurveInfo struct {
+	Name    string       `json:"name"`
+	Builtin bool         `json:"builtin"`
+	Bits    int          `json:"bits"`
+	Params  CurveParams  `json:"params"`
+	Report  *CurveReport `json:"report,omitempty"`
+}
+
+func newCurveInfo(c *Curve, withReport bool) CurveInfo {
+	_, builtin := ecCurves[c.Name]
+	info := CurveInfo{Name: c.Name, Builtin: builtin, Bits: c.P.BitLen(), Params: c.params()}
+	if withReport {
+		info.Report = checkCurve(c)
+	}
+	return info
+}
+
+// GET /curves: danh sách đường cong; POST /curves: kiểm tra và đăng ký đường cong mới
+func curvesHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		infos := []CurveInfo{}
+		for _, c := range listCurves() {
+			infos = append(infos, newCurveInfo(c, false))
+		}
+		json.NewEncoder(w).Encode(infos)
+
+	case http.MethodPost:
+		var params CurveParams
+		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+			http.Error(w, "Invalid request", http.StatusBadRequest)
+			return
+		}
+		c, report, err := addCustomCurve(params)
+		if err != nil {
+			if report != nil && !report.Valid {
+				// Trả về báo cáo để biết bước kiểm tra nào thất bại
+				w.WriteHeader(http.StatusUnprocessableEntity)
+				json.NewEncoder(w).Encode(report)
+				return
+			}
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		info := newCurveInfo(c, false)
+		info.Report = report
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(info)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// POST /curves/validate: kiểm tra tham số đường cong mà không đăng ký
+func validateCurveHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var params CurveParams
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	c, err := params.curve()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	json.NewEncoder(w).Encode(checkCurve(c))
+}
+
+// POST /curves/explore: cấu trúc nhóm điểm của đường cong nhỏ
+func exploreCurveHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req ExploreCurveRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	resp, err := exploreCurve(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+// GET /curves/{name}: tham số và báo cáo kiểm tra; DELETE /curves/{name}: xóa đường cong tự định nghĩa
+func curveHandler(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+
+	switch r.Method {
+	case http.MethodGet:
+		c, ok := lookupCurve(name)
+		if !ok {
+			http.Error(w, "Curve not found", http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(newCurveInfo(c, true))
+
+	case http.MethodDelete:
+		if _, ok := ecCurves[name]; ok {
+			http.Error(w, "Cannot delete a built-in curve", http.StatusConflict)
+			return
+		}
+		if err := deleteCustomCurve(name); err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func TestCheckCurveBuiltins(t *testing.T) {
+	for name, c := range ecCurves {
+		if report := checkCurve(c); !report.Valid || !report.Secure {
+			t.Errorf("%s: %+v", name, report.Checks)
+		}
+	}
+}
+
+func TestCheckCurveRejectsOversizedParameters(t *testing.T) {
+	base := ecCurves["P-521"]
+
+	// p lớn hơn giới hạn bị từ chối trước khi kiểm tra số nguyên tố
+	huge := *base
+	huge.P = new(big.Int).Lsh(big.NewInt(1), 1<<20)
+	huge.P.Sub(huge.P, big.NewInt(1))
+	if report := checkCurve(&huge); report.Valid || len(report.Checks) != 1 {
+		t.Fatalf("p %d bit: %+v", huge.P.BitLen(), report.Checks)
+	}
+
+	bigN := *base
+	bigN.N = new(big.Int).Lsh(base.P, 2)
+	if report := checkCurve(&bigN); report.Valid || len(report.Checks) != 1 {
+		t.Fatalf("n %d bit: %+v", bigN.N.BitLen(), report.Checks)
+	}
+
+	// Tham số hex quá dài bị từ chối ngay khi đọc
+	params := base.params()
+	params.A = strings.Repeat("f", 1<<16)
+	if _, err := params.curve(); err == nil {
+		t.Fatal("chấp nhận tham số a dài 2^18 bit")
+	}
+}
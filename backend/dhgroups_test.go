@@ -0,0 +1,45 @@
+package main
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestDHGroupsAreSafePrimeGroups(t *testing.T) {
+	want := map[string]int{
+		"modp2048": 2048, "modp3072": 3072, "modp4096": 4096, "modp6144": 6144, "modp8192": 8192,
+		"ffdhe2048": 2048, "ffdhe3072": 3072, "ffdhe4096": 4096, "ffdhe6144": 6144, "ffdhe8192": 8192,
+	}
+	if len(dhGroups) != len(want) {
+		t.Fatalf("có %d nhóm, cần %d", len(dhGroups), len(want))
+	}
+
+	one := big.NewInt(1)
+	for name, bits := range want {
+		group, err := lookupDHGroup(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		p, q, g := group.P, group.Q, group.G
+		if p.BitLen() != bits {
+			t.Errorf("%s: p dài %d bit", name, p.BitLen())
+		}
+
+		// p = 2q + 1 với p và q đều là số nguyên tố (Baillie-PSW, đủ cho số đã công bố)
+		if new(big.Int).Add(new(big.Int).Lsh(q, 1), one).Cmp(p) != 0 {
+			t.Errorf("%s: p khác 2q + 1", name)
+		}
+		// Với -short bỏ qua các nhóm 6144/8192 bit vì kiểm tra mất vài giây
+		if (!testing.Short() || bits <= 4096) && (!p.ProbablyPrime(0) || !q.ProbablyPrime(0)) {
+			t.Errorf("%s: p không phải số nguyên tố an toàn", name)
+		}
+
+		// q nguyên tố nên g^q = 1 và g khác 1 nghĩa là g có bậc đúng bằng q
+		if g.Cmp(one) <= 0 || g.Cmp(new(big.Int).Sub(p, one)) >= 0 {
+			t.Errorf("%s: g nằm ngoài [2, p-2]", name)
+		}
+		if new(big.Int).Exp(g, q, p).Cmp(one) != 0 {
+			t.Errorf("%s: g không sinh nhóm con bậc q", name)
+		}
+	}
+}
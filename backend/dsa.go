@@ -0,0 +1,318 @@
+// dsa.go
+package main
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Chữ ký DSA theo FIPS 186-4: tham số miền (p, q, g) sinh bằng SHA-256 theo A.1.1.2 và A.2.3
+// để kiểm tra lại được từ seed (A.1.1.3, A.2.4), ký và xác thực trong nhóm con bậc q (4.6, 4.7).
+
+// Các cặp (L, N) được hỗ trợ: độ dài bit của p và q
+var dsaParameterSizes = map[int][]int{
+	2048: {224, 256},
+	3072: {256},
+}
+
+// Chỉ số index dùng khi sinh phần tử sinh g chính tắc (A.2.3)
+const dsaGeneratorIndex = 1
+
+// Khóa DSA: tham số miền p, q, g, khóa bí mật x và khóa công khai y = g^x mod p.
+// Seed và Counter là dữ liệu đã dùng để sinh p, q; Seed rỗng nếu tham số không kiểm tra lại được.
+type DSAKey struct {
+	P, Q, G, X, Y *big.Int
+	Seed          []byte
+	Counter       int
+}
+
+// Kiểm tra cặp (L, N) có trong FIPS 186-4
+func checkDSAParameterSizes(L, N int) error {
+	for _, n := range dsaParameterSizes[L] {
+		if n == N {
+			return nil
+		}
+	}
+	return fmt.Errorf("kích thước tham số DSA không được hỗ trợ: L = %d, N = %d (chỉ hỗ trợ 2048/224, 2048/256, 3072/256)", L, N)
+}
+
+// Tạo khóa DSA với tham số miền mới có độ dài L, N (mặc định 2048/256)
+func generateDSAKey(L, N int) (*DSAKey, error) {
+	if L == 0 {
+		L = 2048
+	}
+	if N == 0 {
+		N = 256
+	}
+	if err := checkDSAParameterSizes(L, N); err != nil {
+		return nil, err
+	}
+
+	p, q, seed, counter, err := generateDSAPQ(L, N)
+	if err != nil {
+		return nil, err
+	}
+	g, err := dsaCanonicalGenerator(p, q, seed)
+	if err != nil {
+		return nil, err
+	}
+	x, err := randScalar(q)
+	if err != nil {
+		return nil, err
+	}
+	y := new(big.Int).Exp(g, x, p)
+
+	return &DSAKey{P: p, Q: q, G: g, X: x, Y: y, Seed: seed, Counter: counter}, nil
+}
+
+// Kiểm tra số nguyên tố: sàng bằng các số nguyên tố nhỏ trước rồi mới dùng Miller-Rabin
+func isProbablePrime(n *big.Int) bool {
+	rem := new(big.Int)
+	for _, sp := range smallPrimes {
+		if n.Cmp(sp) == 0 {
+			return true
+		}
+		if rem.Mod(n, sp).Sign() == 0 {
+			return false
+		}
+	}
+	return n.ProbablyPrime(20)
+}
+
+// Sinh p, q theo A.1.1.2 với SHA-256, trả về kèm seed và counter
+func generateDSAPQ(L, N int) (*big.Int, *big.Int, []byte, int, error) {
+	for {
+		seed := make([]byte, N/8)
+		if _, err := rand.Read(seed); err != nil {
+			return nil, nil, nil, 0, err
+		}
+		q := dsaQFromSeed(seed, N)
+		if !isProbablePrime(q) {
+			continue
+		}
+		if p, counter := dsaFindP(seed, q, L); p != nil {
+			return p, q, seed, counter, nil
+		}
+	}
+}
+
+// q = 2^(N-1) + U + 1 - (U mod 2) với U = SHA-256(seed) mod 2^(N-1)
+func dsaQFromSeed(seed []byte, N int) *big.Int {
+	hash := sha256.Sum256(seed)
+	u := new(big.Int).SetBytes(hash[:])
+	u.Mod(u, new(big.Int).Lsh(big.NewInt(1), uint(N-1)))
+
+	q := new(big.Int).Lsh(big.NewInt(1), uint(N-1))
+	q.Add(q, u).Add(q, big.NewInt(1))
+	return q.Sub(q, big.NewInt(int64(u.Bit(0))))
+}
+
+// Tìm p nguyên tố L bit với q | p - 1 từ seed, thử counter = 0 .. 4L-1.
+// Trả về p và counter của lần thử đầu tiên thành công, p = nil nếu không tìm được.
+func dsaFindP(seed []byte, q *big.Int, L int) (*big.Int, int) {
+	for counter := 0; counter < 4*L; counter++ {
+		if p := dsaCandidateP(seed, q, L, counter); p != nil && isProbablePrime(p) {
+			return p, counter
+		}
+	}
+	return nil, 0
+}
+
+// Ứng viên p ở lần thử counter (A.1.1.2 bước 11), nil nếu p < 2^(L-1):
+// W = V_0 + V_1*2^outlen + ... + (V_n mod 2^b)*2^(n*outlen) với V_j = SHA-256((seed + offset + j) mod 2^seedlen),
+// offset = 1 + counter*(n+1), X = W + 2^(L-1), p = X - (X mod 2q - 1) để p ≡ 1 (mod 2q)
+func dsaCandidateP(seed []byte, q *big.Int, L, counter int) *big.Int {
+	outlen := sha256.Size * 8
+	n := (L+outlen-1)/outlen - 1
+	b := L - 1 - n*outlen
+
+	one := big.NewInt(1)
+	seedMod := new(big.Int).Lsh(one, uint(len(seed)*8))
+	highBit := new(big.Int).Lsh(one, uint(L-1))
+	buf := make([]byte, len(seed))
+
+	offset := 1 + counter*(n+1)
+	w := new(big.Int)
+	for j := 0; j <= n; j++ {
+		v := new(big.Int).SetBytes(seed)
+		v.Add(v, big.NewInt(int64(offset+j))).Mod(v, seedMod).FillBytes(buf)
+		hash := sha256.Sum256(buf)
+		vj := new(big.Int).SetBytes(hash[:])
+		if j == n {
+			vj.Mod(vj, new(big.Int).Lsh(one, uint(b)))
+		}
+		w.Add(w, vj.Lsh(vj, uint(j*outlen)))
+	}
+
+	x := w.Add(w, highBit)
+	c := new(big.Int).Mod(x, new(big.Int).Lsh(q, 1))
+	p := x.Sub(x, c.Sub(c, one))
+	if p.Cmp(highBit) < 0 {
+		return nil
+	}
+	return p
+}
+
+// Phần tử sinh chính tắc theo A.2.3: g = W^((p-1)/q) mod p,
+// W = SHA-256(seed || "ggen" || index || count) với count tăng dần đến khi g >= 2
+func dsaCanonicalGenerator(p, q *big.Int, seed []byte) (*big.Int, error) {
+	e := new(big.Int).Sub(p, big.NewInt(1))
+	e.Div(e, q)
+	for count := 1; count < 1<<16; count++ {
+		u := append([]byte{}, seed...)
+		u = append(u, "ggen"...)
+		u = append(u, dsaGeneratorIndex, byte(count>>8), byte(count))
+		hash := sha256.Sum256(u)
+
+		g := new(big.Int).Exp(new(big.Int).SetBytes(hash[:]), e, p)
+		if g.Cmp(big.NewInt(2)) >= 0 {
+			return g, nil
+		}
+	}
+	return nil, fmt.Errorf("không tìm được phần tử sinh g")
+}
+
+// Kiểm tra tham số miền và khóa DSA:
+// (L, N) hợp lệ, p, q nguyên tố, q | p - 1, 2 <= g <= p - 1, g^q = 1 mod p, 0 < x < q, y = g^x mod p.
+// Nếu có seed, q, p (ở lần thử counter) và g phải trùng với giá trị sinh lại từ seed theo A.1.1.3, A.2.4;
+// không kiểm tra lại các ứng viên trước counter vì mỗi lần thử cần một phép kiểm tra nguyên tố.
+func validateDSAKey(key *DSAKey) error {
+	one := big.NewInt(1)
+	L, N := key.P.BitLen(), key.Q.BitLen()
+	if err := checkDSAParameterSizes(L, N); err != nil {
+		return err
+	}
+	if !isProbablePrime(key.Q) {
+		return fmt.Errorf("q không phải số nguyên tố")
+	}
+	if !isProbablePrime(key.P) {
+		return fmt.Errorf("p không phải số nguyên tố")
+	}
+	if new(big.Int).Mod(new(big.Int).Sub(key.P, one), key.Q).Sign() != 0 {
+		return fmt.Errorf("q không chia hết p - 1")
+	}
+	if key.G.Cmp(one) <= 0 || key.G.Cmp(key.P) >= 0 {
+		return fmt.Errorf("g phải thỏa 2 <= g <= p - 1")
+	}
+	if new(big.Int).Exp(key.G, key.Q, key.P).Cmp(one) != 0 {
+		return fmt.Errorf("g không sinh nhóm con bậc q")
+	}
+
+	if len(key.Seed) > 0 {
+		if len(key.Seed)*8 < N || key.Counter < 0 || key.Counter >= 4*L {
+			return fmt.Errorf("seed hoặc counter của tham số DSA không hợp lệ")
+		}
+		if dsaQFromSeed(key.Seed, N).Cmp(key.Q) != 0 {
+			return fmt.Errorf("q không khớp với seed")
+		}
+		if p := dsaCandidateP(key.Seed, key.Q, L, key.Counter); p == nil || p.Cmp(key.P) != 0 {
+			return fmt.Errorf("p không khớp với seed và counter")
+		}
+		if g, err := dsaCanonicalGenerator(key.P, key.Q, key.Seed); err != nil || g.Cmp(key.G) != 0 {
+			return fmt.Errorf("g không phải phần tử sinh chính tắc của seed")
+		}
+	}
+
+	if key.X.Sign() <= 0 || key.X.Cmp(key.Q) >= 0 {
+		return fmt.Errorf("khóa bí mật x phải thỏa 0 < x < q")
+	}
+	if new(big.Int).Exp(key.G, key.X, key.P).Cmp(key.Y) != 0 {
+		return fmt.Errorf("khóa công khai y không bằng g^x mod p")
+	}
+	return nil
+}
+
+// Ký giá trị băm h trong nhóm con bậc q của Z_p* (DSA, ElGamal kiểu DSA):
+// r = (g^k mod p) mod q, s = k^-1 (h + x*r) mod q, k lấy lần lượt từ nextK cho đến khi r, s khác 0
+func signSubgroup(p, q, g, x, h *big.Int, nextK nonceFunc, tr *Trace) (*big.Int, *big.Int, error) {
+	for {
+		k, err := nextK()
+		if err != nil {
+			return nil, nil, err
+		}
+		gk := new(big.Int).Exp(g, k, p)
+		r := new(big.Int).Mod(gk, q)
+		if r.Sign() == 0 {
+			continue
+		}
+		kInv := new(big.Int).ModInverse(k, q)
+		s := new(big.Int).Mul(x, r)
+		s.Add(s, h).Mul(s, kInv).Mod(s, q)
+		if s.Sign() == 0 {
+			continue
+		}
+
+		// Không ghi k, k^-1: biết k là tính được x = (s*k - h)*r^-1 mod q
+		tr.add("g^k mod p", gk)
+		tr.add("r = (g^k mod p) mod q", r)
+		tr.add("s = k^-1 (h + x*r) mod q", s)
+		return r, s, nil
+	}
+}
+
+// Xác thực chữ ký (r, s) của h: 0 < r, s < q, w = s^-1 mod q,
+// v = (g^(h*w) * y^(r*w) mod p) mod q phải bằng r
+func verifySubgroup(p, q, g, y, h, r, s *big.Int, tr *Trace) bool {
+	if !inOpenRange(r, q) || !inOpenRange(s, q) {
+		return false
+	}
+
+	w := new(big.Int).ModInverse(s, q)
+	u1 := new(big.Int).Mul(h, w)
+	u1.Mod(u1, q)
+	u2 := new(big.Int).Mul(r, w)
+	u2.Mod(u2, q)
+	v := new(big.Int).Mul(new(big.Int).Exp(g, u1, p), new(big.Int).Exp(y, u2, p))
+	v.Mod(v, p).Mod(v, q)
+
+	tr.add("w = s^-1 mod q", w)
+	tr.add("u1 = h*w mod q", u1)
+	tr.add("u2 = r*w mod q", u2)
+	tr.add("v = (g^u1 * y^u2 mod p) mod q", v)
+
+	return v.Cmp(r) == 0
+}
+
+// Đọc chữ ký "r,s" (hex)
+func decodeHexSignaturePair(signature string) (*big.Int, *big.Int, error) {
+	parts := strings.Split(signature, ",")
+	if len(parts) != 2 {
+		return nil, nil, fmt.Errorf("sai định dạng chữ ký")
+	}
+	values, err := parseHexInts(parts[0], parts[1])
+	if err != nil {
+		return nil, nil, fmt.Errorf("sai định dạng chữ ký")
+	}
+	return values[0], values[1], nil
+}
+
+// Hàm ký DSA: h là SHA-256(m) giữ N bit trái, chữ ký "r,s" (hex)
+func signDSA(key *DSAKey, message, nonce string, tr *Trace) (string, error) {
+	h := hashToScalar(message, key.Q)
+	tr.add("h = SHA-256(m) (cắt theo q)", h)
+	hash := sha256.Sum256([]byte(message))
+	nextK := newNonceFunc(nonce, key.Q, key.X, crypto.SHA256, hash[:])
+	r, s, err := signSubgroup(key.P, key.Q, key.G, key.X, h, nextK, tr)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x,%x", r, s), nil
+}
+
+// Hàm xác thực chữ ký DSA
+func verifyDSA(key *DSAKey, message, signature string, tr *Trace) (bool, error) {
+	r, s, err := decodeHexSignaturePair(signature)
+	if err != nil {
+		return false, err
+	}
+	tr.add("r", r)
+	tr.add("s", s)
+
+	h := hashToScalar(message, key.Q)
+	tr.add("h = SHA-256(m) (cắt theo q)", h)
+	return verifySubgroup(key.P, key.Q, key.G, key.Y, h, r, s, tr), nil
+}
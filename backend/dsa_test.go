@@ -0,0 +1,193 @@
+package main
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+// Tham số miền DSA 2048/256 sinh bằng OpenSSL theo FIPS 186-4 A.1.1.2 (SHA-256)
+// và A.2.3 (index = 1), kèm seed và counter để sinh lại
+var dsaTestParams = struct {
+	p, q, g, seed string
+	counter       int
+}{
+	p: "81ac03630866432426921ed70ff0057a6c7f32d51e155c4ff6226c2f21d06eff" +
+		"7a82b7879ab3f5ff315b8d1f058e50c22825f6a9144a6d67d90348df5399130d" +
+		"c51c9cb9fc8f92c397746cd0698cac16401b385be24172964f542b12fa23593c" +
+		"bf77b8d7d18fa84beb0b7f7def69b96aaabe11bf0d69bb5a73ce949526b20dfe" +
+		"9f7ddf885d913ba7fe15086a37850d621d31dd68ce2527046fd7fc7a70a52250" +
+		"3c79ee893e074cdc386b7315bdd83951184141234829cf23fe895d8124750e40" +
+		"ed7132761b4a53b57695f4aaf5109dc998fa49a5c7aeb343e18814b6377cdc20" +
+		"14a5f5642ab80908fd48812c40cc751dbf72f2aa32fbbf495455dc04b2003165",
+	q: "96d8a3540c90a8aae1c7f88a87068d631bdf034dceeb475bfde6cafc4c4c30fb",
+	g: "25f9ba37d41ea59786a897c6db59716616491ed259365a73d3135d9b00dc028d" +
+		"639c3a4616977d80262e020d8246a95bd5ec7da16b16e2710acee8f79ddb2132" +
+		"f3d66a4ed3883a7f08966860c39247e7e09edd73ca4130e4eeac6073572a658a" +
+		"c1310bb709d52511c96fa9c8cb684e97303b47c3bc26e3bef2816ac3855d65a5" +
+		"5ac7c8f4c2a4767aa687b7b904e58aa2845aa424da0301079ed011de10c5e6c4" +
+		"749ff40fefcc81bb5666ed0b2dbc26f2f0a5785a7cdf79fbcc830e454b71f4ad" +
+		"35a14b83aed4703c377b5e4dae8d64c8794c6d5c041b5bb668a75fd1aa520ef6" +
+		"221e1123f93a00d9afe1aaf74d3ff204f8df88542e40b4b2803d1bc1cf5ab455",
+	seed:    "44aa32cbc363a236024980622c409d28bff12b3fce0a06696ce213da9cbc1d92",
+	counter: 1602,
+}
+
+// Khóa DSA với tham số miền ở trên và khóa bí mật x ngẫu nhiên
+func testDSAKey(t *testing.T) *DSAKey {
+	t.Helper()
+	values, err := parseHexInts(dsaTestParams.p, dsaTestParams.q, dsaTestParams.g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seed, err := hex.DecodeString(dsaTestParams.seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := &DSAKey{P: values[0], Q: values[1], G: values[2], Seed: seed, Counter: dsaTestParams.counter}
+	if key.X, err = randScalar(key.Q); err != nil {
+		t.Fatal(err)
+	}
+	key.Y = new(big.Int).Exp(key.G, key.X, key.P)
+	return key
+}
+
+func TestDSADomainParametersFromSeed(t *testing.T) {
+	key := testDSAKey(t)
+
+	// A.1.1.3: sinh lại q, p từ seed, p phải xuất hiện đúng ở lần thử counter
+	if q := dsaQFromSeed(key.Seed, key.Q.BitLen()); q.Cmp(key.Q) != 0 {
+		t.Fatalf("q sinh lại từ seed: %x", q)
+	}
+	p, counter := dsaFindP(key.Seed, key.Q, key.P.BitLen())
+	if p == nil || p.Cmp(key.P) != 0 || counter != dsaTestParams.counter {
+		t.Fatalf("p sinh lại từ seed ở counter %d", counter)
+	}
+
+	// A.2.4: g là phần tử sinh chính tắc của seed
+	g, err := dsaCanonicalGenerator(key.P, key.Q, key.Seed)
+	if err != nil || g.Cmp(key.G) != 0 {
+		t.Fatalf("g sinh lại từ seed: %v", err)
+	}
+	if err := validateDSAKey(key); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDSAValidateRejectsTamperedParameters(t *testing.T) {
+	one := big.NewInt(1)
+	cases := map[string]func(k *DSAKey){
+		"counter khác": func(k *DSAKey) { k.Counter++ },
+		"counter âm":   func(k *DSAKey) { k.Counter = -1 },
+		"seed khác":    func(k *DSAKey) { k.Seed[len(k.Seed)-1] ^= 1 },
+		"seed ngắn":    func(k *DSAKey) { k.Seed = k.Seed[:16] },
+		"g không chính tắc": func(k *DSAKey) {
+			k.G = new(big.Int).Exp(k.G, big.NewInt(2), k.P)
+			k.Y = new(big.Int).Exp(k.G, k.X, k.P)
+		},
+		"g = 1":                func(k *DSAKey) { k.G = one },
+		"y sai":                func(k *DSAKey) { k.Y = new(big.Int).Add(k.Y, one) },
+		"x = 0":                func(k *DSAKey) { k.X = new(big.Int) },
+		"p không nguyên tố":    func(k *DSAKey) { k.P = new(big.Int).Add(k.P, big.NewInt(2)) },
+		"q không chia hết p-1": func(k *DSAKey) { k.Seed = nil; k.Q = new(big.Int).Add(k.Q, big.NewInt(2)) },
+	}
+	for name, mutate := range cases {
+		key := testDSAKey(t)
+		mutate(key)
+		if err := validateDSAKey(key); err == nil {
+			t.Errorf("%s: tham số sai vẫn hợp lệ", name)
+		}
+	}
+}
+
+func TestDSASignVerifyRoundTrip(t *testing.T) {
+	key := testDSAKey(t)
+	message := "xin chào DSA"
+
+	for _, nonce := range []string{NonceRandom, NonceRFC6979} {
+		signature, err := signDSA(key, message, nonce, nil)
+		if err != nil {
+			t.Fatalf("%s: %v", nonce, err)
+		}
+		if ok, err := verifyDSA(key, message, signature, nil); !ok || err != nil {
+			t.Fatalf("%s: chữ ký hợp lệ bị từ chối: %v", nonce, err)
+		}
+	}
+
+	// RFC 6979 cho chữ ký xác định
+	s1, _ := signDSA(key, message, NonceRFC6979, nil)
+	s2, _ := signDSA(key, message, NonceRFC6979, nil)
+	if s1 != s2 {
+		t.Fatal("chữ ký RFC 6979 không tái lập được")
+	}
+}
+
+func TestDSAVerifyRejectsTampering(t *testing.T) {
+	key := testDSAKey(t)
+	message := "xin chào DSA"
+	signature, err := signDSA(key, message, NonceRandom, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, s, err := decodeHexSignaturePair(signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pair := func(r, s *big.Int) string { return fmt.Sprintf("%x,%x", r, s) }
+	one := big.NewInt(1)
+
+	cases := map[string]string{
+		"r + 1":    pair(new(big.Int).Add(r, one), s),
+		"s + 1":    pair(r, new(big.Int).Add(s, one)),
+		"r = 0":    pair(new(big.Int), s),
+		"s = 0":    pair(r, new(big.Int)),
+		"r + q":    pair(new(big.Int).Add(r, key.Q), s),
+		"s + q":    pair(r, new(big.Int).Add(s, key.Q)),
+		"đổi r, s": pair(s, r),
+	}
+	for name, sig := range cases {
+		if ok, _ := verifyDSA(key, message, sig, nil); ok {
+			t.Errorf("%s: chữ ký bị sửa vẫn hợp lệ", name)
+		}
+	}
+	if ok, _ := verifyDSA(key, message+".", signature, nil); ok {
+		t.Error("chấp nhận chữ ký cho thông điệp khác")
+	}
+	if ok, _ := verifyDSA(testDSAKey(t), message, signature, nil); ok {
+		t.Error("chấp nhận chữ ký với khóa khác")
+	}
+	for _, sig := range []string{"", "abc", "1,2,3", "xyz,1"} {
+		if _, err := verifyDSA(key, message, sig, nil); err == nil {
+			t.Errorf("chữ ký %q sai định dạng không báo lỗi", sig)
+		}
+	}
+}
+
+func TestDSASignTraceHidesNonce(t *testing.T) {
+	key := testDSAKey(t)
+	message := "xin chào DSA"
+
+	tr := newTrace(true)
+	if _, err := signDSA(key, message, NonceRFC6979, tr); err != nil {
+		t.Fatal(err)
+	}
+
+	// k tính lại được vì RFC 6979 xác định
+	hash := sha256.Sum256([]byte(message))
+	k, err := rfc6979Nonces(key.Q, key.X, crypto.SHA256, hash[:])()
+	if err != nil {
+		t.Fatal(err)
+	}
+	kInv := new(big.Int).ModInverse(k, key.Q)
+	for _, step := range tr.Steps() {
+		for _, secret := range []*big.Int{k, kInv, key.X} {
+			if strings.Contains(step.Value, secret.Text(16)) {
+				t.Errorf("trace chứa giá trị bí mật ở bước %q", step.Name)
+			}
+		}
+	}
+}
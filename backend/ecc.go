@@ -2,10 +2,13 @@
 package main
 
 import (
+	"crypto"
+	"crypto/ecdh"
 	"crypto/ecdsa"
-	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
 	"errors"
 	"fmt"
 	"math"
@@ -18,8 +21,10 @@ var curveA = big.NewInt(2)
 var curveB = big.NewInt(3)
 var curveP = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 521), big.NewInt(1)) // P = 2^521 - 1
 
-var eccPrivateKey *big.Int
-var eccPublicKeyX, eccPublicKeyY *big.Int
+// Khóa ECC trên đường cong tự định nghĩa: khóa bí mật D và điểm công khai (X, Y)
+type ECCKey struct {
+	D, X, Y *big.Int
+}
 
 // Hàm tính modulo nghịch đảo
 func modInverse(k, p *big.Int) *big.Int {
@@ -43,57 +48,108 @@ func findOnePointOnCurve() (*big.Int, *big.Int, error) {
 }
 
 // Hàm sinh khóa ECC
-func generateECCKeys() {
-	eccPrivateKey, _ = rand.Int(rand.Reader, curveP)
-	eccPublicKeyX, eccPublicKeyY, _ = findOnePointOnCurve()
-	fmt.Println("ECC Private Key:", eccPrivateKey)
-	fmt.Println("ECC Public Key:", eccPublicKeyX, eccPublicKeyY)
+func generateECCKeys() (*ECCKey, error) {
+	d, err := rand.Int(rand.Reader, curveP)
+	if err != nil {
+		return nil, err
+	}
+	x, y, err := findOnePointOnCurve()
+	if err != nil {
+		return nil, err
+	}
+	return &ECCKey{D: d, X: x, Y: y}, nil
+}
+
+// Đường cong tự định nghĩa ban đầu, không có điểm sinh và bậc
+var legacyCurve = &Curve{Name: "legacy", P: curveP, A: curveA, B: curveB}
+
+// Điểm trên đường cong dạng JSON; điểm vô cực có infinity = true
+type ECPoint struct {
+	X        *big.Int `json:"x,omitempty"`
+	Y        *big.Int `json:"y,omitempty"`
+	Infinity bool     `json:"infinity,omitempty"`
+}
+
+func newECPoint(x, y *big.Int) ECPoint {
+	if x == nil {
+		return ECPoint{Infinity: true}
+	}
+	return ECPoint{X: x, Y: y}
+}
+
+// Một bước của thuật toán double-and-add
+type LadderStep struct {
+	Bit    int     `json:"bit"`
+	Op     string  `json:"op"`
+	Result ECPoint `json:"result"`
+}
+
+// Hàm nhân điểm trên đường cong elliptic (double-and-add từ bit cao nhất).
+// Điểm vô cực được biểu diễn bằng (nil, nil); k không bị thay đổi.
+func pointMultiply(c *Curve, k *big.Int, x, y *big.Int) (*big.Int, *big.Int) {
+	return pointMultiplyLadder(c, k, x, y, nil)
 }
 
-// Hàm nhân điểm trên đường cong elliptic
-func pointMultiply(k *big.Int, x, y *big.Int) (*big.Int, *big.Int) {
-	rx, ry := big.NewInt(0), big.NewInt(0)
-	tempX, tempY := x, y
+// Nhân điểm như pointMultiply, ghi lại từng bước vào steps nếu steps khác nil
+func pointMultiplyLadder(c *Curve, k *big.Int, x, y *big.Int, steps *[]LadderStep) (*big.Int, *big.Int) {
+	var rx, ry *big.Int
 
-	for k.Cmp(big.NewInt(0)) > 0 {
-		if new(big.Int).And(k, big.NewInt(1)).Cmp(big.NewInt(1)) == 0 {
-			rx, ry = pointAdd(rx, ry, tempX, tempY)
+	for i := k.BitLen() - 1; i >= 0; i-- {
+		rx, ry = pointAdd(c, rx, ry, rx, ry)
+		if steps != nil {
+			*steps = append(*steps, LadderStep{Bit: i, Op: "double", Result: newECPoint(rx, ry)})
+		}
+		if k.Bit(i) == 1 {
+			rx, ry = pointAdd(c, rx, ry, x, y)
+			if steps != nil {
+				*steps = append(*steps, LadderStep{Bit: i, Op: "add", Result: newECPoint(rx, ry)})
+			}
 		}
-		tempX, tempY = pointAdd(tempX, tempY, tempX, tempY)
-		k.Rsh(k, 1)
 	}
 
 	return rx, ry
 }
 
 // Hàm cộng hai điểm trên đường cong elliptic
-func pointAdd(x1, y1, x2, y2 *big.Int) (*big.Int, *big.Int) {
-	if x1.Cmp(big.NewInt(0)) == 0 && y1.Cmp(big.NewInt(0)) == 0 {
+func pointAdd(c *Curve, x1, y1, x2, y2 *big.Int) (*big.Int, *big.Int) {
+	if x1 == nil {
 		return x2, y2
 	}
-	if x2.Cmp(big.NewInt(0)) == 0 && y2.Cmp(big.NewInt(0)) == 0 {
+	if x2 == nil {
 		return x1, y1
 	}
 
 	var m *big.Int
-	if x1.Cmp(x2) == 0 && y1.Cmp(y2) == 0 {
-		num := new(big.Int).Add(new(big.Int).Mul(big.NewInt(3), new(big.Int).Mul(x1, x1)), curveA)
+	if x1.Cmp(x2) == 0 {
+		// P + (-P) = O, bao gồm cả trường hợp nhân đôi điểm có y = 0
+		if new(big.Int).Mod(new(big.Int).Add(y1, y2), c.P).Sign() == 0 {
+			return nil, nil
+		}
+		num := new(big.Int).Add(new(big.Int).Mul(big.NewInt(3), new(big.Int).Mul(x1, x1)), c.A)
 		den := new(big.Int).Mul(big.NewInt(2), y1)
-		m = new(big.Int).Mod(new(big.Int).Mul(num, modInverse(den, curveP)), curveP)
+		m = new(big.Int).Mod(new(big.Int).Mul(num, modInverse(den, c.P)), c.P)
 	} else {
 		num := new(big.Int).Sub(y2, y1)
-		den := new(big.Int).Sub(x2, x1)
-		m = new(big.Int).Mod(new(big.Int).Mul(num, modInverse(den, curveP)), curveP)
+		den := new(big.Int).Mod(new(big.Int).Sub(x2, x1), c.P)
+		m = new(big.Int).Mod(new(big.Int).Mul(num, modInverse(den, c.P)), c.P)
 	}
 
-	rx := new(big.Int).Mod(new(big.Int).Sub(new(big.Int).Mul(m, m), new(big.Int).Add(x1, x2)), curveP)
-	ry := new(big.Int).Mod(new(big.Int).Sub(new(big.Int).Mul(m, new(big.Int).Sub(x1, rx)), y1), curveP)
+	rx := new(big.Int).Mod(new(big.Int).Sub(new(big.Int).Mul(m, m), new(big.Int).Add(x1, x2)), c.P)
+	ry := new(big.Int).Mod(new(big.Int).Sub(new(big.Int).Mul(m, new(big.Int).Sub(x1, rx)), y1), c.P)
 
 	return rx, ry
 }
 
+// Điểm đối -P = (x, -y)
+func pointNeg(c *Curve, x, y *big.Int) (*big.Int, *big.Int) {
+	if x == nil {
+		return nil, nil
+	}
+	return x, new(big.Int).Mod(new(big.Int).Neg(y), c.P)
+}
+
 // Hàm mã hóa ECC với việc chia nhỏ thông điệp thành các khối
-func encryptECC(message string) (string, error) {
+func encryptECC(key *ECCKey, message string, tr *Trace) (string, error) {
     // Kích thước khối
     blockSize := 60
     var encryptedMessage string
@@ -112,9 +168,13 @@ func encryptECC(message string) (string, error) {
         k, _ := rand.Int(rand.Reader, curveP)
 
         // Tính toán điểm C1 và C2
-        C1x, C1y := pointMultiply(k, eccPublicKeyX, eccPublicKeyY)
-        Px, Py := pointMultiply(k, eccPublicKeyX, eccPublicKeyY)
-        C2x, C2y := pointAdd(msgInt, big.NewInt(0), Px, Py)
+        tr.setBlock(start/blockSize + 1)
+        tr.add("m", msgInt)
+        tr.add("k", k)
+        C1x, C1y := tr.pointMultiply("C1 = k*P", legacyCurve, k, key.X, key.Y)
+        Px, Py := tr.pointMultiply("k*P", legacyCurve, k, key.X, key.Y)
+        C2x, C2y := pointAdd(legacyCurve, msgInt, big.NewInt(0), Px, Py)
+        tr.addPoint("C2 = (m, 0) + k*P", C2x, C2y)
 
         // Kết hợp C1, C2 thành một chuỗi
         encryptedMessage += fmt.Sprintf("%s|%s|%s|%s|", C1x.String(), C1y.String(), C2x.String(), C2y.String())
@@ -128,7 +188,7 @@ func encryptECC(message string) (string, error) {
 
 
 // Hàm giải mã ECC với việc xử lý từng khối
-func decryptECC(encryptedMessage string) (string, error) {
+func decryptECC(key *ECCKey, encryptedMessage string, tr *Trace) (string, error) {
     parts := strings.Split(encryptedMessage, "|")
     if len(parts)%4 != 0 {
         return "", errors.New("Invalid encrypted message format")
@@ -138,17 +198,31 @@ func decryptECC(encryptedMessage string) (string, error) {
 
     // Giải mã từng khối
     for i := 0; i < len(parts); i += 4 {
-        C1x, _ := new(big.Int).SetString(parts[i], 10)
-        C1y, _ := new(big.Int).SetString(parts[i+1], 10)
-        C2x, _ := new(big.Int).SetString(parts[i+2], 10)
-        C2y, _ := new(big.Int).SetString(parts[i+3], 10)
+        var coords [4]*big.Int
+        for j := range coords {
+            v, ok := new(big.Int).SetString(parts[i+j], 10)
+            if !ok {
+                return "", errors.New("sai định dạng bản mã")
+            }
+            // Tọa độ phải thuộc [0, p-1]
+            if v.Sign() < 0 || v.Cmp(curveP) >= 0 {
+                return "", errors.New("tọa độ trong bản mã nằm ngoài khoảng [0, p-1]")
+            }
+            coords[j] = v
+        }
+        C1x, C1y, C2x, C2y := coords[0], coords[1], coords[2], coords[3]
 
         // Tính toán điểm tempX và tempY bằng việc nhân điểm C1 với khóa riêng
-        tempX, tempY := pointMultiply(eccPrivateKey, C1x, C1y)
-        tempY.Neg(tempY).Mod(tempY, curveP)
+        tr.setBlock(i/4 + 1)
+        tempX, tempY := pointMultiply(legacyCurve, key.D, C1x, C1y)
+        tempX, tempY = pointNeg(legacyCurve, tempX, tempY)
 
         // Tính toán Mx bằng cách cộng C2 với điểm temp
-        Mx, _ := pointAdd(C2x, C2y, tempX, tempY)
+        Mx, _ := pointAdd(legacyCurve, C2x, C2y, tempX, tempY)
+        if Mx == nil {
+            return "", errors.New("Invalid encrypted message format")
+        }
+        tr.add("Mx = (C2 - d*C1).x", Mx)
 
         // Chuyển đổi Mx thành chuỗi và thêm vào thông điệp đã giải mã
         decryptedMessage += string(Mx.Bytes())
@@ -158,70 +232,209 @@ func decryptECC(encryptedMessage string) (string, error) {
 }
 
 
-// Định nghĩa tham số đường cong elliptic (P-521 curve)
-var curve = elliptic.P521()
+// Đường cong của khóa ECDSA và hàm băm đi kèm
+type ecdsaCurve struct {
+	ECDH ecdh.Curve
+	Hash crypto.Hash
+}
 
-// Khai báo khóa ECC
-var privateKey *ecdsa.PrivateKey
-var publicKey *ecdsa.PublicKey
+// Các đường cong hỗ trợ cho khóa ECDSA (mặc định P-521), mỗi đường cong ký với hàm băm tương ứng
+var ecdsaCurves = map[string]ecdsaCurve{
+	"P-256": {ecdh.P256(), crypto.SHA256},
+	"P-384": {ecdh.P384(), crypto.SHA384},
+	"P-521": {ecdh.P521(), crypto.SHA512},
+}
 
-// Hàm sinh khóa ECC
-func generateECCKey() error {
-	var err error
-	privateKey, err = ecdsa.GenerateKey(curve, rand.Reader)
+// Hàm sinh khóa ECC: sinh bằng crypto/ecdh rồi chuyển sang *ecdsa.PrivateKey qua PKCS#8
+func generateECCKey(curveName string) (*ecdsa.PrivateKey, error) {
+	curve, ok := ecdsaCurves[curveName]
+	if !ok {
+		return nil, fmt.Errorf("đường cong không được hỗ trợ: %s", curveName)
+	}
+	priv, err := curve.ECDH.GenerateKey(rand.Reader)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	publicKey = &privateKey.PublicKey
-	fmt.Println("ECC Private Key:", privateKey.D)
-	fmt.Println("ECC Public Key:", publicKey.X, publicKey.Y)
-	return nil
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := parsed.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("loại khóa không mong đợi: %T", parsed)
+	}
+	return key, nil
 }
 
-// Hàm ký thông điệp sử dụng ECC
-func signECC(message string) (string, error) {
-	// Băm thông điệp
-	hash := sha256.New()
-	hash.Write([]byte(message))
-	hashedMessage := hash.Sum(nil)
+// Hàm băm dùng khi ký bằng khóa ECDSA: SHA-256, SHA-384 hoặc SHA-512 theo đường cong
+func ecdsaHash(pub *ecdsa.PublicKey) crypto.Hash {
+	if curve, ok := ecdsaCurves[pub.Curve.Params().Name]; ok {
+		return curve.Hash
+	}
+	return crypto.SHA256
+}
 
-	// Ký thông điệp với khóa riêng
-	r, s, err := ecdsa.Sign(rand.Reader, privateKey, hashedMessage)
+// Băm thông điệp bằng hàm băm h
+func hashMessage(h crypto.Hash, message string) []byte {
+	hasher := h.New()
+	hasher.Write([]byte(message))
+	return hasher.Sum(nil)
+}
+
+// Tọa độ khóa công khai ECDSA (big-endian, đủ độ dài) lấy từ dạng không nén 0x04 || X || Y
+func ecdsaPublicPoint(pub *ecdsa.PublicKey) (x, y []byte, err error) {
+	ecdhKey, err := pub.ECDH()
+	if err != nil {
+		return nil, nil, err
+	}
+	point := ecdhKey.Bytes()
+	size := (len(point) - 1) / 2
+	return point[1 : 1+size], point[1+size:], nil
+}
+
+// Hàm ký thông điệp sử dụng ECC, chữ ký theo định dạng legacy, der hoặc p1363.
+// nonce chọn k ngẫu nhiên hoặc xác định theo RFC 6979.
+func signECC(privateKey *ecdsa.PrivateKey, message, format, nonce string) (string, error) {
+	r, s, err := signECDSA(privateKey, message, nonce)
 	if err != nil {
 		return "", err
 	}
 
 	// Trả về chữ ký dưới dạng chuỗi
-	signature := fmt.Sprintf("%s|%s", r.Text(16), s.Text(16))
-	return signature, nil
+	return encodeECDSASignature(&privateKey.PublicKey, r, s, format)
 }
 
-// Hàm xác minh chữ ký ECC
-func verifyECC(message, signature string) (bool, error) {
-	// Băm thông điệp
-	hash := sha256.New()
-	hash.Write([]byte(message))
-	hashedMessage := hash.Sum(nil)
+// Ký thông điệp đã băm bằng hàm băm của đường cong, trả về (r, s)
+func signECDSA(privateKey *ecdsa.PrivateKey, message, nonce string) (*big.Int, *big.Int, error) {
+	// Băm thông điệp bằng hàm băm của đường cong
+	hash := ecdsaHash(&privateKey.PublicKey)
+	hashedMessage := hashMessage(hash, message)
+
+	// Ký thông điệp với khóa riêng
+	if nonce == NonceRFC6979 {
+		// Với rand = nil, crypto/ecdsa ký xác định theo RFC 6979 bằng phép toán thời gian hằng
+		der, err := privateKey.Sign(nil, hashedMessage, hash)
+		if err != nil {
+			return nil, nil, err
+		}
+		var sig ecdsaDERSignature
+		if _, err := asn1.Unmarshal(der, &sig); err != nil {
+			return nil, nil, err
+		}
+		return sig.R, sig.S, nil
+	}
+	return ecdsa.Sign(rand.Reader, privateKey, hashedMessage)
+}
 
+// Hàm xác minh chữ ký ECC, tự nhận ra định dạng chữ ký.
+// hash là hàm băm ghi trên phiên bản khóa, 0 với phiên bản tạo trước khi ghi hàm băm.
+func verifyECC(publicKey *ecdsa.PublicKey, hash crypto.Hash, message, signature string) (bool, error) {
 	// Phân tách chữ ký thành r và s
-	parts := strings.Split(signature, "|")
-	if len(parts) != 2 {
-		return false, errors.New("invalid signature format")
+	r, s, format, err := decodeECDSASignature(publicKey, signature)
+	if err != nil {
+		return false, err
 	}
 
-	r := new(big.Int)
-	r, ok := r.SetString(parts[0], 16)
-	if !ok {
-		return false, errors.New("invalid r value in signature")
+	// Xác minh chữ ký với khóa công khai, băm bằng hàm băm của đường cong.
+	// Chỉ phiên bản khóa cũ mới chấp nhận chữ ký dạng cũ trên SHA-256.
+	if hash == 0 {
+		hash = ecdsaHash(publicKey)
+		if format == ECDSAFormatLegacy && hash != crypto.SHA256 && ecdsa.Verify(publicKey, hashMessage(crypto.SHA256, message), r, s) {
+			return true, nil
+		}
 	}
+	return ecdsa.Verify(publicKey, hashMessage(hash, message), r, s), nil
+}
 
-	s := new(big.Int)
-	s, ok = s.SetString(parts[1], 16)
-	if !ok {
-		return false, errors.New("invalid s value in signature")
+// Băm thông điệp và lấy n.BitLen() bit bên trái làm số nguyên e (như ECDSA chuẩn)
+func hashToScalar(message string, n *big.Int) *big.Int {
+	hash := sha256.Sum256([]byte(message))
+	return bitsToInt(hash[:], n.BitLen())
+}
+
+// Ký ECDSA theo sách giáo khoa trên đường cong của khóa EC:
+// R = k*G, r = R.x mod n, s = k^-1 (e + r*d) mod n. Chữ ký: "r,s" (hex)
+func signECDSATextbook(key *ECKey, message, nonce string, tr *Trace) (string, error) {
+	c := key.Curve
+	e := hashToScalar(message, c.N)
+	tr.add("e = H(m)", e)
+
+	hash := sha256.Sum256([]byte(message))
+	r, s, err := ecdsaSignScalar(c, key.D, e, newNonceFunc(nonce, c.N, key.D, crypto.SHA256, hash[:]), tr)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x,%x", r, s), nil
+}
+
+// Ký số nguyên e bằng khóa bí mật d, k lấy lần lượt từ nextK cho đến khi r, s khác 0
+func ecdsaSignScalar(c *Curve, d, e *big.Int, nextK nonceFunc, tr *Trace) (*big.Int, *big.Int, error) {
+	for {
+		k, err := nextK()
+		if err != nil {
+			return nil, nil, err
+		}
+		rx, ry := pointMultiply(c, k, c.Gx, c.Gy)
+		if rx == nil {
+			continue
+		}
+		r := new(big.Int).Mod(rx, c.N)
+		if r.Sign() == 0 {
+			continue
+		}
+		kInv := modInverse(k, c.N)
+		s := new(big.Int).Mul(r, d)
+		s.Add(s, e)
+		s.Mul(s, kInv)
+		s.Mod(s, c.N)
+		if s.Sign() == 0 {
+			continue
+		}
+		tr.addPoint("R = k*G", rx, ry)
+		tr.add("r = R.x mod n", r)
+		tr.add("s = k^-1*(e + r*d) mod n", s)
+		return r, s, nil
 	}
+}
 
-	// Xác minh chữ ký với khóa công khai
-	valid := ecdsa.Verify(publicKey, hashedMessage, r, s)
-	return valid, nil
+// Xác minh chữ ký ECDSA sách giáo khoa: X = (e*w)*G + (r*w)*Q với w = s^-1, hợp lệ nếu X.x mod n = r
+func verifyECDSATextbook(key *ECKey, message, signature string, tr *Trace) (bool, error) {
+	c := key.Curve
+
+	parts := strings.Split(signature, ",")
+	if len(parts) != 2 {
+		return false, errors.New("invalid signature format")
+	}
+	values, err := parseHexInts(parts...)
+	if err != nil {
+		return false, err
+	}
+	r, s := values[0], values[1]
+	if r.Sign() <= 0 || r.Cmp(c.N) >= 0 || s.Sign() <= 0 || s.Cmp(c.N) >= 0 {
+		return false, nil
+	}
+
+	e := hashToScalar(message, c.N)
+	w := modInverse(s, c.N)
+	u1 := new(big.Int).Mod(new(big.Int).Mul(e, w), c.N)
+	u2 := new(big.Int).Mod(new(big.Int).Mul(r, w), c.N)
+	tr.add("e = H(m)", e)
+	tr.add("w = s^-1 mod n", w)
+	tr.add("u1 = e*w mod n", u1)
+	tr.add("u2 = r*w mod n", u2)
+
+	x1, y1 := tr.pointMultiply("u1*G", c, u1, c.Gx, c.Gy)
+	x2, y2 := tr.pointMultiply("u2*Q", c, u2, key.X, key.Y)
+	x, y := pointAdd(c, x1, y1, x2, y2)
+	tr.addPoint("X = u1*G + u2*Q", x, y)
+	if x == nil {
+		return false, nil
+	}
+	v := new(big.Int).Mod(x, c.N)
+	tr.add("v = X.x mod n", v)
+	return v.Cmp(r) == 0, nil
 }
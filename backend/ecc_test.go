@@ -0,0 +1,27 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestECCDecryptRejectsMalformedCiphertext(t *testing.T) {
+	key, err := generateECCKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, malformed := range map[string]string{
+		"không phải số":  "a|1|2|3",
+		"phần rỗng":      "1||2|3",
+		"tọa độ âm":      "1|-1|2|3",
+		"tọa độ bằng p":  fmt.Sprintf("1|2|%s|3", curveP),
+		"thiếu tọa độ":   "1|2|3",
+		"khối thứ hai":   "1|2|3|4|1|2|3|x",
+		"số thập lục ff": "ff|1|2|3",
+	} {
+		if _, err := decryptECC(key, malformed, nil); err == nil {
+			t.Errorf("%s: chấp nhận bản mã sai", name)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+// ecdsasig.go
+package main
+
+import (
+	"crypto/ecdsa"
+	"encoding/asn1"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Định dạng chữ ký ECDSA do /sign trả về
+const (
+	ECDSAFormatLegacy = "legacy" // "r|s" dạng hex (mặc định)
+	ECDSAFormatDER    = "der"    // base64 của SEQUENCE { r INTEGER, s INTEGER } theo RFC 3279 (OpenSSL)
+	ECDSAFormatP1363  = "p1363"  // base64 của r || s độ dài cố định theo IEEE P1363 (WebCrypto, JWS)
+)
+
+// Chuẩn hóa định dạng chữ ký trong yêu cầu ký
+func parseECDSAFormat(format string) (string, error) {
+	switch f := strings.ToLower(format); f {
+	case "":
+		return ECDSAFormatLegacy, nil
+	case ECDSAFormatLegacy, ECDSAFormatDER, ECDSAFormatP1363:
+		return f, nil
+	}
+	return "", fmt.Errorf("định dạng chữ ký ECDSA không được hỗ trợ: %s", format)
+}
+
+type ecdsaDERSignature struct {
+	R, S *big.Int
+}
+
+// Độ dài theo byte của mỗi thành phần r, s trong dạng P1363
+func ecdsaScalarSize(pub *ecdsa.PublicKey) int {
+	return (pub.Curve.Params().N.BitLen() + 7) / 8
+}
+
+// Mã hóa chữ ký (r, s) theo định dạng đã chọn
+func encodeECDSASignature(pub *ecdsa.PublicKey, r, s *big.Int, format string) (string, error) {
+	switch format {
+	case ECDSAFormatDER:
+		der, err := asn1.Marshal(ecdsaDERSignature{R: r, S: s})
+		if err != nil {
+			return "", err
+		}
+		return base64.StdEncoding.EncodeToString(der), nil
+	case ECDSAFormatP1363:
+		size := ecdsaScalarSize(pub)
+		out := make([]byte, 2*size)
+		r.FillBytes(out[:size])
+		s.FillBytes(out[size:])
+		return base64.StdEncoding.EncodeToString(out), nil
+	}
+	return fmt.Sprintf("%s|%s", r.Text(16), s.Text(16)), nil
+}
+
+// Đọc chữ ký ở một trong ba định dạng, trả về r, s và định dạng đã nhận ra.
+// Dạng cũ chứa "|"; dạng base64 được thử DER trước rồi đến P1363 (đúng 2 lần độ dài thành phần).
+func decodeECDSASignature(pub *ecdsa.PublicKey, signature string) (*big.Int, *big.Int, string, error) {
+	if strings.Contains(signature, "|") {
+		parts := strings.Split(signature, "|")
+		if len(parts) != 2 {
+			return nil, nil, "", errors.New("invalid signature format")
+		}
+		r, ok := new(big.Int).SetString(parts[0], 16)
+		if !ok {
+			return nil, nil, "", errors.New("invalid r value in signature")
+		}
+		s, ok := new(big.Int).SetString(parts[1], 16)
+		if !ok {
+			return nil, nil, "", errors.New("invalid s value in signature")
+		}
+		return r, s, ECDSAFormatLegacy, nil
+	}
+
+	data, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return nil, nil, "", errors.New("invalid signature format")
+	}
+
+	var der ecdsaDERSignature
+	if rest, err := asn1.Unmarshal(data, &der); err == nil && len(rest) == 0 {
+		if der.R.Sign() <= 0 || der.S.Sign() <= 0 {
+			return nil, nil, "", errors.New("invalid signature format")
+		}
+		return der.R, der.S, ECDSAFormatDER, nil
+	}
+
+	if size := ecdsaScalarSize(pub); len(data) == 2*size {
+		return new(big.Int).SetBytes(data[:size]), new(big.Int).SetBytes(data[size:]), ECDSAFormatP1363, nil
+	}
+	return nil, nil, "", errors.New("invalid signature format")
+}
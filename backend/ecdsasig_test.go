@@ -0,0 +1,121 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"math/big"
+	"testing"
+)
+
+func TestECDSASignatureFormatsRoundTrip(t *testing.T) {
+	for _, curve := range []elliptic.Curve{elliptic.P256(), elliptic.P384(), elliptic.P521()} {
+		name := curve.Params().Name
+		priv, err := ecdsa.GenerateKey(curve, rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pub := &priv.PublicKey
+		hash := sha256.Sum256([]byte("hello"))
+
+		// Chữ ký DER của thư viện chuẩn được nhận ra và chuyển được sang P1363
+		der, err := ecdsa.SignASN1(rand.Reader, priv, hash[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		r, s, format, err := decodeECDSASignature(pub, base64.StdEncoding.EncodeToString(der))
+		if err != nil || format != ECDSAFormatDER {
+			t.Fatalf("%s: đọc DER: %s, %v", name, format, err)
+		}
+
+		p1363, err := encodeECDSASignature(pub, r, s, ECDSAFormatP1363)
+		if err != nil {
+			t.Fatal(err)
+		}
+		raw, _ := base64.StdEncoding.DecodeString(p1363)
+		if len(raw) != 2*ecdsaScalarSize(pub) {
+			t.Fatalf("%s: P1363 dài %d byte", name, len(raw))
+		}
+		r2, s2, format, err := decodeECDSASignature(pub, p1363)
+		if err != nil || format != ECDSAFormatP1363 || r2.Cmp(r) != 0 || s2.Cmp(s) != 0 {
+			t.Fatalf("%s: đọc P1363: %s, %v", name, format, err)
+		}
+
+		// P1363 -> DER cho lại đúng chữ ký ban đầu
+		der2, err := encodeECDSASignature(pub, r2, s2, ECDSAFormatDER)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if der2 != base64.StdEncoding.EncodeToString(der) {
+			t.Fatalf("%s: DER khác sau khi đổi qua P1363", name)
+		}
+
+		legacy, _ := encodeECDSASignature(pub, r, s, ECDSAFormatLegacy)
+		r3, s3, format, err := decodeECDSASignature(pub, legacy)
+		if err != nil || format != ECDSAFormatLegacy || r3.Cmp(r) != 0 || s3.Cmp(s) != 0 {
+			t.Fatalf("%s: đọc dạng cũ: %s, %v", name, format, err)
+		}
+		if !ecdsa.Verify(pub, hash[:], r3, s3) {
+			t.Fatalf("%s: chữ ký không hợp lệ sau khi đổi định dạng", name)
+		}
+	}
+}
+
+func TestECDSAP1363PadsSmallScalars(t *testing.T) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub := &priv.PublicKey
+
+	p1363, err := encodeECDSASignature(pub, big.NewInt(1), big.NewInt(2), ECDSAFormatP1363)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, s, format, err := decodeECDSASignature(pub, p1363)
+	if err != nil || format != ECDSAFormatP1363 || r.Int64() != 1 || s.Int64() != 2 {
+		t.Fatalf("r = %v, s = %v, %s, %v", r, s, format, err)
+	}
+}
+
+func TestECDSADecodeRejectsMalformedSignatures(t *testing.T) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub := &priv.PublicKey
+	hash := sha256.Sum256([]byte("hello"))
+	der, err := ecdsa.SignASN1(rand.Reader, priv, hash[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	b64 := base64.StdEncoding.EncodeToString
+
+	cases := map[string]string{
+		"rỗng":                 "",
+		"không phải base64":    "!!!",
+		"dạng cũ sai hex":      "zz|01",
+		"dạng cũ thừa phần":    "01|02|03",
+		"DER bị cắt":           b64(der[:len(der)-1]),
+		"DER thừa byte":        b64(append(append([]byte{}, der...), 0x00)),
+		"DER r âm":             b64([]byte{0x30, 0x06, 0x02, 0x01, 0xff, 0x02, 0x01, 0x01}),
+		"DER s bằng 0":         b64([]byte{0x30, 0x06, 0x02, 0x01, 0x01, 0x02, 0x01, 0x00}),
+		"DER không tối giản":   b64([]byte{0x30, 0x07, 0x02, 0x02, 0x00, 0x01, 0x02, 0x01, 0x01}),
+		"DER sai thẻ":          b64([]byte{0x31, 0x06, 0x02, 0x01, 0x01, 0x02, 0x01, 0x01}),
+		"P1363 sai độ dài":     b64(make([]byte, 63)),
+		"P1363 quá dài":        b64(make([]byte, 66)),
+		"DER chỉ có một số":    b64([]byte{0x30, 0x03, 0x02, 0x01, 0x01}),
+		"DER độ dài sai":       b64([]byte{0x30, 0x09, 0x02, 0x01, 0x01, 0x02, 0x01, 0x01}),
+		"DER số nguyên rỗng":   b64([]byte{0x30, 0x05, 0x02, 0x00, 0x02, 0x01, 0x01}),
+		"DER kiểu không đúng":  b64([]byte{0x30, 0x06, 0x04, 0x01, 0x01, 0x02, 0x01, 0x01}),
+		"base64 không đệm đủ":  "MEUCIQ",
+		"dạng cũ thiếu phần s": "01|",
+	}
+	for name, signature := range cases {
+		if r, s, format, err := decodeECDSASignature(pub, signature); err == nil {
+			t.Errorf("%s: chấp nhận chữ ký sai (r = %v, s = %v, %s)", name, r, s, format)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+// ecelgamal.go
+package main
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Hệ số Koblitz: thông điệp m được nhúng thành điểm có x = m*K + j với 0 <= j < K
+var koblitzK = big.NewInt(256)
+
+// Khóa EC-ElGamal: khóa bí mật D và điểm công khai Q = D*G trên một đường cong tự chọn
+type ECKey struct {
+	Curve *Curve
+	D     *big.Int
+	X, Y  *big.Int
+}
+
+// Hàm sinh khóa EC-ElGamal: d ngẫu nhiên trong [1, n-1], Q = d*G
+func generateECKey(curve *Curve) (*ECKey, error) {
+	d, err := randScalar(curve.N)
+	if err != nil {
+		return nil, err
+	}
+	x, y := pointMultiply(curve, d, curve.Gx, curve.Gy)
+	return &ECKey{Curve: curve, D: d, X: x, Y: y}, nil
+}
+
+// Số ngẫu nhiên trong [1, n-1]
+func randScalar(n *big.Int) (*big.Int, error) {
+	k, err := rand.Int(rand.Reader, new(big.Int).Sub(n, big.NewInt(1)))
+	if err != nil {
+		return nil, err
+	}
+	return k.Add(k, big.NewInt(1)), nil
+}
+
+// Kiểm tra khóa EC-ElGamal đọc từ keystore: Q nằm trên đường cong và Q = d*G
+func validateECKey(key *ECKey) error {
+	if key.D.Sign() <= 0 || key.D.Cmp(key.Curve.N) >= 0 {
+		return errors.New("khóa bí mật d phải nằm trong [1, n-1]")
+	}
+	if !key.Curve.isOnCurve(key.X, key.Y) {
+		return errors.New("khóa công khai không nằm trên đường cong")
+	}
+	x, y := pointMultiply(key.Curve, key.D, key.Curve.Gx, key.Curve.Gy)
+	if x == nil || x.Cmp(key.X) != 0 || y.Cmp(key.Y) != 0 {
+		return errors.New("khóa công khai không khớp với khóa bí mật")
+	}
+	return nil
+}
+
+// Số byte thông điệp trong một khối: cần (0x01 || khối)*K + K < p.
+// Byte 0x01 ở đầu giữ lại các byte 0 đứng đầu khối.
+func koblitzBlockSize(c *Curve) int {
+	return (c.P.BitLen()-1-koblitzK.BitLen())/8 - 1
+}
+
+// Nhúng một khối thông điệp thành điểm trên đường cong bằng phương pháp Koblitz
+func koblitzEncode(c *Curve, block []byte) (*big.Int, *big.Int, error) {
+	m := new(big.Int).SetBytes(append([]byte{1}, block...))
+	base := new(big.Int).Mul(m, koblitzK)
+
+	for j := int64(0); j < koblitzK.Int64(); j++ {
+		x := new(big.Int).Add(base, big.NewInt(j))
+		if y := new(big.Int).ModSqrt(c.rhs(x), c.P); y != nil {
+			return x, y, nil
+		}
+	}
+	return nil, nil, errors.New("không nhúng được khối thông điệp thành điểm trên đường cong")
+}
+
+// Lấy lại khối thông điệp từ hoành độ của điểm: m = floor(x / K)
+func koblitzDecode(x *big.Int) ([]byte, error) {
+	m := new(big.Int).Quo(x, koblitzK).Bytes()
+	if len(m) == 0 || m[0] != 1 {
+		return nil, errors.New("điểm giải mã không phải là thông điệp hợp lệ")
+	}
+	return m[1:], nil
+}
+
+// Mã hóa EC-ElGamal từng khối: M = Koblitz(khối), C1 = k*G, C2 = M + k*Q.
+// Bản mã: các khối "C1x,C1y,C2x,C2y" (hex) nối bằng "|".
+func encryptECElGamal(key *ECKey, message string, tr *Trace) (string, error) {
+	c := key.Curve
+	blockSize := koblitzBlockSize(c)
+	if blockSize < 1 {
+		return "", fmt.Errorf("đường cong %s quá nhỏ để nhúng thông điệp", c.Name)
+	}
+
+	var blocks []string
+	data := []byte(message)
+	for start := 0; start < len(data) || start == 0; start += blockSize {
+		end := min(start+blockSize, len(data))
+		tr.setBlock(start/blockSize + 1)
+
+		mx, my, err := koblitzEncode(c, data[start:end])
+		if err != nil {
+			return "", err
+		}
+
+		tr.add("m", data[start:end])
+		tr.add("j = x mod K (Koblitz)", new(big.Int).Mod(mx, koblitzK))
+		tr.addPoint("M", mx, my)
+
+		k, err := randScalar(c.N)
+		if err != nil {
+			return "", err
+		}
+		tr.add("k", k)
+		c1x, c1y := tr.pointMultiply("C1 = k*G", c, k, c.Gx, c.Gy)
+		sx, sy := tr.pointMultiply("S = k*Q", c, k, key.X, key.Y)
+		c2x, c2y := pointAdd(c, mx, my, sx, sy)
+		tr.addPoint("C2 = M + S", c2x, c2y)
+		if c2x == nil {
+			return "", errors.New("không thể mã hóa khối thông điệp")
+		}
+
+		blocks = append(blocks, fmt.Sprintf("%x,%x,%x,%x", c1x, c1y, c2x, c2y))
+	}
+	return strings.Join(blocks, "|"), nil
+}
+
+// Giải mã EC-ElGamal từng khối: M = C2 - d*C1
+func decryptECElGamal(key *ECKey, encryptedMessage string, tr *Trace) (string, error) {
+	c := key.Curve
+
+	var message []byte
+	for i, block := range strings.Split(encryptedMessage, "|") {
+		tr.setBlock(i + 1)
+		parts := strings.Split(block, ",")
+		if len(parts) != 4 {
+			return "", errors.New("sai định dạng bản mã")
+		}
+		values, err := parseHexInts(parts...)
+		if err != nil {
+			return "", err
+		}
+		c1x, c1y, c2x, c2y := values[0], values[1], values[2], values[3]
+		if !c.isOnCurve(c1x, c1y) || !c.isOnCurve(c2x, c2y) {
+			return "", errors.New("điểm trong bản mã không nằm trên đường cong")
+		}
+
+		tr.addPoint("C1", c1x, c1y)
+		tr.addPoint("C2", c2x, c2y)
+		sx, sy := pointMultiply(c, key.D, c1x, c1y)
+		tr.addPoint("S = d*C1", sx, sy)
+		sx, sy = pointNeg(c, sx, sy)
+		tr.addPoint("-S", sx, sy)
+		mx, my := pointAdd(c, c2x, c2y, sx, sy)
+		tr.addPoint("M = C2 - S", mx, my)
+		if mx == nil {
+			return "", errors.New("điểm giải mã không phải là thông điệp hợp lệ")
+		}
+
+		plain, err := koblitzDecode(mx)
+		if err != nil {
+			return "", err
+		}
+		tr.add("m = floor(x / K)", plain)
+		message = append(message, plain...)
+	}
+	return string(message), nil
+}
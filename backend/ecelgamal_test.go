@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func testECKey(t *testing.T, curveName string) *ECKey {
+	t.Helper()
+	key, err := generateECKey(ecCurves[curveName])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// Tách khối đầu tiên của bản mã thành C1x, C1y, C2x, C2y
+func ecElGamalBlock(t *testing.T, ciphertext string) []*big.Int {
+	t.Helper()
+	block, _, _ := strings.Cut(ciphertext, "|")
+	values, err := parseHexInts(strings.Split(block, ",")...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return values
+}
+
+func TestECElGamalRoundTrip(t *testing.T) {
+	for _, name := range []string{"P-256", "secp256k1"} {
+		key := testECKey(t, name)
+		long := strings.Repeat("Xin chào EC-ElGamal! ", 10)
+		for _, message := range []string{"", "a", "\x00\x00đầu là byte 0", long} {
+			ciphertext, err := encryptECElGamal(key, message, nil)
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			plaintext, err := decryptECElGamal(key, ciphertext, newTrace(true))
+			if err != nil || plaintext != message {
+				t.Fatalf("%s: giải mã %q, %v", name, plaintext, err)
+			}
+		}
+	}
+}
+
+func TestECElGamalTamperedCiphertext(t *testing.T) {
+	key := testECKey(t, "P-256")
+	c := key.Curve
+	message := "Xin chào EC-ElGamal!"
+	ciphertext, err := encryptECElGamal(key, message, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := ecElGamalBlock(t, ciphertext)
+
+	// Thay C1 hoặc C2 bằng điểm khác vẫn nằm trên đường cong: không được ra thông điệp ban đầu
+	c1x, c1y := pointAdd(c, v[0], v[1], c.Gx, c.Gy)
+	c2x, c2y := pointAdd(c, v[2], v[3], c.Gx, c.Gy)
+	for name, tampered := range map[string]string{
+		"C1 + G": fmt.Sprintf("%x,%x,%x,%x", c1x, c1y, v[2], v[3]),
+		"C2 + G": fmt.Sprintf("%x,%x,%x,%x", v[0], v[1], c2x, c2y),
+	} {
+		if plaintext, err := decryptECElGamal(key, tampered, nil); err == nil && plaintext == message {
+			t.Errorf("%s: bản mã bị sửa vẫn giải mã ra thông điệp ban đầu", name)
+		}
+	}
+
+	// Khóa khác không giải mã được
+	other := testECKey(t, "P-256")
+	if plaintext, err := decryptECElGamal(other, ciphertext, nil); err == nil && plaintext == message {
+		t.Error("giải mã được bằng khóa khác")
+	}
+
+	for name, malformed := range map[string]string{
+		"thiếu thành phần": fmt.Sprintf("%x,%x,%x", v[0], v[1], v[2]),
+		"không phải hex":   fmt.Sprintf("zz,%x,%x,%x", v[1], v[2], v[3]),
+		"rỗng":             "",
+	} {
+		if _, err := decryptECElGamal(key, malformed, nil); err == nil {
+			t.Errorf("%s: chấp nhận bản mã sai định dạng", name)
+		}
+	}
+}
+
+func TestECElGamalRejectsOffCurvePoints(t *testing.T) {
+	key := testECKey(t, "secp256k1")
+	ciphertext, err := encryptECElGamal(key, "Xin chào", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := ecElGamalBlock(t, ciphertext)
+	one := big.NewInt(1)
+
+	// Điểm không nằm trên đường cong có thể rơi vào nhóm có bậc nhỏ và làm lộ d (invalid-curve attack)
+	for name, tampered := range map[string]string{
+		"C1y + 1":  fmt.Sprintf("%x,%x,%x,%x", v[0], new(big.Int).Add(v[1], one), v[2], v[3]),
+		"C2x + 1":  fmt.Sprintf("%x,%x,%x,%x", v[0], v[1], new(big.Int).Add(v[2], one), v[3]),
+		"C1 = 0,0": fmt.Sprintf("0,0,%x,%x", v[2], v[3]),
+		"C1x = p":  fmt.Sprintf("%x,%x,%x,%x", key.Curve.P, v[1], v[2], v[3]),
+	} {
+		if _, err := decryptECElGamal(key, tampered, nil); err == nil {
+			t.Errorf("%s: chấp nhận điểm không nằm trên đường cong", name)
+		}
+	}
+}
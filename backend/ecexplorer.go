@@ -0,0 +1,180 @@
+// ecexplorer.go
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+)
+
+// Giới hạn của trình khám phá đường cong nhỏ
+const (
+	explorerMaxP     = 4096 // p lớn nhất được liệt kê điểm
+	explorerMaxTable = 64   // số điểm lớn nhất để trả về bảng cộng
+)
+
+// Yêu cầu khám phá đường cong y^2 = x^3 + ax + b trên F_p với p nhỏ
+type ExploreCurveRequest struct {
+	P int64 `json:"p"`
+	A int64 `json:"a"`
+	B int64 `json:"b"`
+
+	// Trả về bảng cộng (chỉ số điểm trong danh sách points)
+	Table bool `json:"table,omitempty"`
+
+	// Nhân vô hướng k*point và trả về từng bước double-and-add
+	Scalar *int64   `json:"scalar,omitempty"`
+	Point  *ECPoint `json:"point,omitempty"`
+}
+
+// Một điểm của đường cong kèm bậc của nó
+type ExplorerPoint struct {
+	ECPoint
+	Order int64 `json:"order"`
+}
+
+// Vết nhân vô hướng k*P
+type LadderTrace struct {
+	Scalar int64        `json:"scalar"`
+	Point  ECPoint      `json:"point"`
+	Steps  []LadderStep `json:"steps"`
+	Result ECPoint      `json:"result"`
+}
+
+// Cấu trúc nhóm điểm của đường cong
+type ExploreCurveResponse struct {
+	P      int64           `json:"p"`
+	A      int64           `json:"a"`
+	B      int64           `json:"b"`
+	Order  int64           `json:"order"`
+	Cyclic bool            `json:"cyclic"`
+	Points []ExplorerPoint `json:"points"`
+	Table  [][]int         `json:"table,omitempty"`
+	Ladder *LadderTrace    `json:"ladder,omitempty"`
+}
+
+// Liệt kê điểm, bậc của nhóm và bậc từng điểm; tùy chọn bảng cộng và vết nhân vô hướng
+func exploreCurve(req ExploreCurveRequest) (*ExploreCurveResponse, error) {
+	if req.P <= 3 || req.P > explorerMaxP || !big.NewInt(req.P).ProbablyPrime(20) {
+		return nil, fmt.Errorf("p phải là số nguyên tố trong khoảng (3, %d]", explorerMaxP)
+	}
+	p := big.NewInt(req.P)
+	c := &Curve{
+		Name: "toy",
+		P:    p,
+		A:    new(big.Int).Mod(big.NewInt(req.A), p),
+		B:    new(big.Int).Mod(big.NewInt(req.B), p),
+	}
+	if c.discriminant().Sign() == 0 {
+		return nil, errors.New("4a^3 + 27b^2 = 0 (mod p), đường cong suy biến")
+	}
+
+	// Điểm vô cực đứng đầu danh sách, sau đó theo x rồi y tăng dần
+	points := []ExplorerPoint{{ECPoint: ECPoint{Infinity: true}}}
+	for x := big.NewInt(0); x.Cmp(p) < 0; x = new(big.Int).Add(x, big.NewInt(1)) {
+		y := new(big.Int).ModSqrt(c.rhs(x), p)
+		if y == nil {
+			continue
+		}
+		points = append(points, ExplorerPoint{ECPoint: ECPoint{X: x, Y: y}})
+		if y.Sign() != 0 {
+			points = append(points, ExplorerPoint{ECPoint: ECPoint{X: x, Y: new(big.Int).Sub(p, y)}})
+		}
+	}
+	sort.SliceStable(points[1:], func(i, j int) bool {
+		a, b := points[1+i], points[1+j]
+		if a.X.Cmp(b.X) != 0 {
+			return a.X.Cmp(b.X) < 0
+		}
+		return a.Y.Cmp(b.Y) < 0
+	})
+
+	resp := &ExploreCurveResponse{P: req.P, A: c.A.Int64(), B: c.B.Int64(), Order: int64(len(points))}
+
+	// Bậc của mỗi điểm là ước nhỏ nhất d của #E sao cho d*P = O (định lý Lagrange)
+	divisors := divisorsOf(resp.Order)
+	for i := range points {
+		for _, d := range divisors {
+			if x, _ := pointMultiply(c, big.NewInt(d), points[i].X, points[i].Y); x == nil {
+				points[i].Order = d
+				break
+			}
+		}
+		if points[i].Order == resp.Order {
+			resp.Cyclic = true
+		}
+	}
+	resp.Points = points
+
+	if req.Table {
+		if len(points) > explorerMaxTable {
+			return nil, fmt.Errorf("bảng cộng chỉ hỗ trợ đường cong có tối đa %d điểm", explorerMaxTable)
+		}
+		index := map[string]int{}
+		for i, pt := range points {
+			index[pointKey(pt.X, pt.Y)] = i
+		}
+		resp.Table = make([][]int, len(points))
+		for i, a := range points {
+			resp.Table[i] = make([]int, len(points))
+			for j, b := range points {
+				x, y := pointAdd(c, a.X, a.Y, b.X, b.Y)
+				resp.Table[i][j] = index[pointKey(x, y)]
+			}
+		}
+	}
+
+	if req.Scalar != nil {
+		if *req.Scalar < 0 {
+			return nil, errors.New("scalar phải không âm")
+		}
+		// Mặc định nhân điểm có bậc lớn nhất
+		point, best := points[0].ECPoint, points[0].Order
+		for _, pt := range points {
+			if pt.Order > best {
+				point, best = pt.ECPoint, pt.Order
+			}
+		}
+		if req.Point != nil {
+			point = *req.Point
+			if point.Infinity {
+				point = ECPoint{Infinity: true}
+			} else if point.X == nil || point.Y == nil || !c.isOnCurve(point.X, point.Y) {
+				return nil, errors.New("điểm không nằm trên đường cong")
+			}
+		}
+
+		trace := &LadderTrace{Scalar: *req.Scalar, Point: point, Steps: []LadderStep{}}
+		x, y := pointMultiplyLadder(c, big.NewInt(*req.Scalar), point.X, point.Y, &trace.Steps)
+		trace.Result = newECPoint(x, y)
+		resp.Ladder = trace
+	}
+
+	return resp, nil
+}
+
+// Khóa của một điểm trong bảng tra chỉ số
+func pointKey(x, y *big.Int) string {
+	if x == nil {
+		return "O"
+	}
+	return x.String() + "," + y.String()
+}
+
+// Các ước dương của n theo thứ tự tăng dần
+func divisorsOf(n int64) []int64 {
+	var small, large []int64
+	for d := int64(1); d*d <= n; d++ {
+		if n%d == 0 {
+			small = append(small, d)
+			if d != n/d {
+				large = append(large, n/d)
+			}
+		}
+	}
+	for i := len(large) - 1; i >= 0; i-- {
+		small = append(small, large[i])
+	}
+	return small
+}
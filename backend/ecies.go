@@ -0,0 +1,142 @@
+// ecies.go
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+// Các đường cong hỗ trợ cho khóa ECIES (mặc định X25519)
+var eciesCurves = map[string]ecdh.Curve{
+	"P-256":  ecdh.P256(),
+	"P-384":  ecdh.P384(),
+	"P-521":  ecdh.P521(),
+	"X25519": ecdh.X25519(),
+}
+
+// Tên đường cong của khóa ECIES
+func eciesCurveName(curve ecdh.Curve) string {
+	for name, c := range eciesCurves {
+		if c == curve {
+			return name
+		}
+	}
+	return ""
+}
+
+// Hàm sinh khóa ECIES
+func generateECIESKey(curveName string) (*ecdh.PrivateKey, error) {
+	curve, ok := eciesCurves[curveName]
+	if !ok {
+		return nil, fmt.Errorf("đường cong không được hỗ trợ: %s", curveName)
+	}
+	return curve.GenerateKey(rand.Reader)
+}
+
+// Dẫn xuất khóa AES-256 và nonce GCM từ bí mật chung ECDH bằng HKDF-SHA256,
+// gắn với khóa công khai tạm thời và khóa công khai của người nhận
+func eciesDeriveKey(shared, ephemeralPublic, recipientPublic []byte) (cipher.AEAD, []byte, error) {
+	info := append([]byte("ECIES-HKDF-SHA256-AES256GCM"), ephemeralPublic...)
+	info = append(info, recipientPublic...)
+
+	okm := make([]byte, 32+12)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, shared, nil, info), okm); err != nil {
+		return nil, nil, err
+	}
+	block, err := aes.NewCipher(okm[:32])
+	if err != nil {
+		return nil, nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, nil, err
+	}
+	return gcm, okm[32:], nil
+}
+
+// Mã hóa ECIES: ECDH với khóa tạm thời, HKDF, AES-256-GCM.
+// Bản mã: base64(khóa công khai tạm thời || ciphertext || tag)
+func encryptECIES(publicKey *ecdh.PublicKey, message string) (string, error) {
+	ephemeral, err := publicKey.Curve().GenerateKey(rand.Reader)
+	if err != nil {
+		return "", err
+	}
+	shared, err := ephemeral.ECDH(publicKey)
+	if err != nil {
+		return "", err
+	}
+
+	ephemeralPublic := ephemeral.PublicKey().Bytes()
+	gcm, nonce, err := eciesDeriveKey(shared, ephemeralPublic, publicKey.Bytes())
+	if err != nil {
+		return "", err
+	}
+
+	out := gcm.Seal(append([]byte{}, ephemeralPublic...), nonce, []byte(message), ephemeralPublic)
+	return base64.StdEncoding.EncodeToString(out), nil
+}
+
+// Giải mã ECIES
+func decryptECIES(privateKey *ecdh.PrivateKey, encryptedMessage string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(encryptedMessage)
+	if err != nil {
+		return "", errors.New("sai định dạng bản mã")
+	}
+
+	// Độ dài khóa công khai tạm thời bằng độ dài khóa công khai của người nhận
+	recipientPublic := privateKey.PublicKey().Bytes()
+	size := len(recipientPublic)
+	if len(data) < size+16 {
+		return "", errors.New("bản mã quá ngắn")
+	}
+
+	ephemeral, err := privateKey.Curve().NewPublicKey(data[:size])
+	if err != nil {
+		return "", errors.New("khóa công khai tạm thời không hợp lệ")
+	}
+	shared, err := privateKey.ECDH(ephemeral)
+	if err != nil {
+		return "", err
+	}
+
+	gcm, nonce, err := eciesDeriveKey(shared, data[:size], recipientPublic)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := gcm.Open(nil, nonce, data[size:], data[:size])
+	if err != nil {
+		return "", errors.New("bản mã đã bị thay đổi hoặc sai khóa")
+	}
+	return string(plaintext), nil
+}
+
+// Chuyển khóa đọc từ PKCS#8 sang khóa ECDH: x509 trả về *ecdsa.PrivateKey
+// cho các đường cong NIST và *ecdh.PrivateKey cho X25519
+func toECDHPrivateKey(parsed any) (*ecdh.PrivateKey, error) {
+	var key *ecdh.PrivateKey
+	switch k := parsed.(type) {
+	case *ecdh.PrivateKey:
+		key = k
+	case *ecdsa.PrivateKey:
+		var err error
+		if key, err = k.ECDH(); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("dữ liệu khóa không phải %s", KeyTypeECIES)
+	}
+	if eciesCurveName(key.Curve()) == "" {
+		return nil, errors.New("đường cong không được hỗ trợ cho ECIES")
+	}
+	return key, nil
+}
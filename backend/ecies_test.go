@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/base64"
+	"testing"
+)
+
+func TestECIESRoundTrip(t *testing.T) {
+	for name := range eciesCurves {
+		key, err := generateECIESKey(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, message := range []string{"", "Xin chào ECIES!"} {
+			ciphertext, err := encryptECIES(key.PublicKey(), message)
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			plaintext, err := decryptECIES(key, ciphertext)
+			if err != nil || plaintext != message {
+				t.Fatalf("%s: giải mã %q, %v", name, plaintext, err)
+			}
+		}
+
+		// Mỗi lần mã hóa dùng khóa tạm thời mới
+		c1, _ := encryptECIES(key.PublicKey(), "m")
+		c2, _ := encryptECIES(key.PublicKey(), "m")
+		if c1 == c2 {
+			t.Fatalf("%s: hai bản mã của cùng thông điệp trùng nhau", name)
+		}
+	}
+}
+
+func TestECIESRejectsTamperedCiphertext(t *testing.T) {
+	for name := range eciesCurves {
+		key, err := generateECIESKey(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ciphertext, err := encryptECIES(key.PublicKey(), "Xin chào ECIES!")
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := base64.StdEncoding.DecodeString(ciphertext)
+		size := len(key.PublicKey().Bytes())
+
+		// Sửa một byte ở khóa tạm thời, phần mã hóa và tag
+		for _, i := range []int{size - 1, size, len(data) - 1} {
+			tampered := append([]byte{}, data...)
+			tampered[i] ^= 0x01
+			if _, err := decryptECIES(key, base64.StdEncoding.EncodeToString(tampered)); err == nil {
+				t.Errorf("%s: giải mã được bản mã bị sửa ở byte %d", name, i)
+			}
+		}
+
+		if _, err := decryptECIES(key, base64.StdEncoding.EncodeToString(data[:size+15])); err == nil {
+			t.Errorf("%s: giải mã được bản mã bị cắt", name)
+		}
+		if _, err := decryptECIES(key, "!!!"); err == nil {
+			t.Errorf("%s: giải mã được bản mã không phải base64", name)
+		}
+
+		other, _ := generateECIESKey(name)
+		if _, err := decryptECIES(other, ciphertext); err == nil {
+			t.Errorf("%s: giải mã được bằng khóa khác", name)
+		}
+	}
+}
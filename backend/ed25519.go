@@ -0,0 +1,69 @@
+// ed25519.go
+package main
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// Tùy chọn ký theo biến thể RFC 8032: "ED25519", "ED25519PH" hoặc "ED25519CTX"
+func ed25519Options(variant, context string) (*ed25519.Options, error) {
+	if len(context) > 255 {
+		return nil, errors.New("context Ed25519 tối đa 255 byte")
+	}
+
+	switch variant {
+	case "ED25519":
+		if context != "" {
+			return nil, errors.New("Ed25519 thuần không dùng context, hãy chọn ED25519CTX hoặc ED25519PH")
+		}
+		return &ed25519.Options{}, nil
+	case "ED25519PH":
+		return &ed25519.Options{Hash: crypto.SHA512, Context: context}, nil
+	case "ED25519CTX":
+		if context == "" {
+			return nil, errors.New("Ed25519ctx yêu cầu context khác rỗng")
+		}
+		return &ed25519.Options{Context: context}, nil
+	}
+	return nil, fmt.Errorf("biến thể Ed25519 không được hỗ trợ: %s", variant)
+}
+
+// Thông điệp thực sự được ký: Ed25519ph ký giá trị băm SHA-512 của thông điệp
+func ed25519Message(opts *ed25519.Options, message string) []byte {
+	if opts.Hash == crypto.SHA512 {
+		digest := sha512.Sum512([]byte(message))
+		return digest[:]
+	}
+	return []byte(message)
+}
+
+// Hàm ký thông điệp bằng Ed25519, chữ ký dạng base64
+func signEd25519(privateKey ed25519.PrivateKey, variant, context, message string) (string, error) {
+	opts, err := ed25519Options(variant, context)
+	if err != nil {
+		return "", err
+	}
+	signature, err := privateKey.Sign(nil, ed25519Message(opts, message), opts)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(signature), nil
+}
+
+// Hàm xác minh chữ ký Ed25519
+func verifyEd25519(publicKey ed25519.PublicKey, variant, context, message, signature string) (bool, error) {
+	opts, err := ed25519Options(variant, context)
+	if err != nil {
+		return false, err
+	}
+	signatureBytes, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return false, errors.New("invalid signature format")
+	}
+	return ed25519.VerifyWithOptions(publicKey, ed25519Message(opts, message), signatureBytes, opts) == nil, nil
+}
@@ -2,32 +2,124 @@
 package main
 
 import (
+	"crypto"
 	"crypto/rand"
+	"crypto/sha256"
 	"fmt"
 	"math/big"
 	"strings"
 )
 
-var p, g, x, y *big.Int
+// Khóa ElGamal: tham số p, g, khóa bí mật x và khóa công khai y = g^x mod p.
+// Q là bậc (nguyên tố) của nhóm con sinh bởi g, p = 2Q + 1; Q bằng nil với khóa cũ
+// được tạo trước khi có sinh số nguyên tố an toàn.
+// Group là tên nhóm chuẩn (modp2048, ffdhe3072, ...) nếu khóa dùng nhóm có sẵn.
+type ElGamalKey struct {
+	Group         string
+	P, G, Q, X, Y *big.Int
+}
+
+// Tạo khóa ElGamal với số nguyên tố an toàn p có đúng bits bit
+func generateElGamalKeys(bits int) (*ElGamalKey, error) {
+	if bits < 128 || bits > 4096 {
+		return nil, fmt.Errorf("kích thước khóa ElGamal phải từ 128 đến 4096 bit")
+	}
+
+	sp, err := takeSafePrime(bits)
+	if err != nil {
+		return nil, err
+	}
+	g, err := findElGamalGenerator(sp.P, sp.Q)
+	if err != nil {
+		return nil, err
+	}
+	return newElGamalKey("", sp.P, g, sp.Q)
+}
 
-// Tạo khóa ElGamal
-func generateElGamalKeys(bits int) {
-	p = new(big.Int)
-	g = new(big.Int)
-	x = new(big.Int)
-	y = new(big.Int)
+// Tạo khóa ElGamal trong một nhóm Diffie-Hellman chuẩn (RFC 3526 / RFC 7919)
+func generateElGamalGroupKeys(name string) (*ElGamalKey, error) {
+	group, err := lookupDHGroup(name)
+	if err != nil {
+		return nil, err
+	}
+	return newElGamalKey(group.Name, group.P, group.G, group.Q)
+}
 
-	p.SetString(generatePrime(bits), 10)
-	g.SetInt64(2)
-	x, _ = rand.Int(rand.Reader, new(big.Int).Sub(p, big.NewInt(2)))
+// Tạo khóa bí mật x ngẫu nhiên trong [1, q-1] và y = g^x mod p
+func newElGamalKey(group string, p, g, q *big.Int) (*ElGamalKey, error) {
+	x, err := rand.Int(rand.Reader, new(big.Int).Sub(q, big.NewInt(1)))
+	if err != nil {
+		return nil, err
+	}
 	x.Add(x, big.NewInt(1))
-	y.Exp(g, x, p)
+	y := new(big.Int).Exp(g, x, p)
+
+	key := &ElGamalKey{Group: group, P: p, G: g, Q: q, X: x, Y: y}
+	if err := validateElGamalKey(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// Chọn phần tử sinh g của nhóm con bậc q: g = h^2 mod p với h ngẫu nhiên, g != 1
+func findElGamalGenerator(p, q *big.Int) (*big.Int, error) {
+	one := big.NewInt(1)
+	pMinus3 := new(big.Int).Sub(p, big.NewInt(3))
+	for {
+		h, err := rand.Int(rand.Reader, pMinus3)
+		if err != nil {
+			return nil, err
+		}
+		h.Add(h, big.NewInt(2)) // h thuộc [2, p-2]
+
+		g := new(big.Int).Exp(h, big.NewInt(2), p)
+		if g.Cmp(one) != 0 && new(big.Int).Exp(g, q, p).Cmp(one) == 0 {
+			return g, nil
+		}
+	}
 }
 
+// Kiểm tra tham số và khóa ElGamal:
+// p, q nguyên tố, p = 2q + 1, 1 < g < p - 1, g^q = 1 mod p, 0 < x < q, y = g^x mod p.
+// Với nhóm chuẩn, tham số phải trùng với nhóm đã đặt tên thay vì kiểm tra lại tính nguyên tố.
+func validateElGamalKey(key *ElGamalKey) error {
+	one := big.NewInt(1)
+	if key.Group != "" {
+		group, err := lookupDHGroup(key.Group)
+		if err != nil {
+			return err
+		}
+		if key.P.Cmp(group.P) != 0 || key.G.Cmp(group.G) != 0 || key.Q.Cmp(group.Q) != 0 {
+			return fmt.Errorf("tham số khóa không khớp với nhóm %s", key.Group)
+		}
+	} else if key.Q == nil {
+		return fmt.Errorf("khóa ElGamal không có bậc nhóm con q")
+	} else if !key.P.ProbablyPrime(20) {
+		return fmt.Errorf("p không phải số nguyên tố")
+	} else if !key.Q.ProbablyPrime(20) {
+		return fmt.Errorf("q không phải số nguyên tố")
+	}
+	if new(big.Int).Add(new(big.Int).Lsh(key.Q, 1), one).Cmp(key.P) != 0 {
+		return fmt.Errorf("p không bằng 2q + 1")
+	}
+	if key.G.Cmp(one) <= 0 || key.G.Cmp(new(big.Int).Sub(key.P, one)) >= 0 {
+		return fmt.Errorf("g phải thỏa 1 < g < p - 1")
+	}
+	if new(big.Int).Exp(key.G, key.Q, key.P).Cmp(one) != 0 {
+		return fmt.Errorf("g không sinh nhóm con bậc q")
+	}
+	if key.X.Sign() <= 0 || key.X.Cmp(key.Q) >= 0 {
+		return fmt.Errorf("khóa bí mật x phải thỏa 0 < x < q")
+	}
+	if new(big.Int).Exp(key.G, key.X, key.P).Cmp(key.Y) != 0 {
+		return fmt.Errorf("khóa công khai y không bằng g^x mod p")
+	}
+	return nil
+}
 
 // Chia thông điệp thành các đoạn nhỏ (an toàn)
-func splitElgamalMessage(message string) ([]string, error) {
-	maxChunkSize := (p.BitLen()-1)/8 - 1 
+func splitElgamalMessage(key *ElGamalKey, message string) ([]string, error) {
+	maxChunkSize := (key.P.BitLen()-1)/8 - 1
 
 	chunks := []string{}
 	runes := []rune(message)
@@ -55,31 +147,47 @@ func splitElgamalMessage(message string) ([]string, error) {
 }
 
 // Mã hóa ElGamal cho thông điệp dài
-func encryptElGamalLong(message string) (string, error) {
-	chunks, err := splitElgamalMessage(message)
+func encryptElGamalLong(key *ElGamalKey, message string, tr *Trace) (string, error) {
+	chunks, err := splitElgamalMessage(key, message)
 	if err != nil {
 		return "", err
 	}
 
 	encryptedChunks := []string{}
-	for _, chunk := range chunks {
-		encryptedChunk, err := encryptElGamal(chunk)
+	for i, chunk := range chunks {
+		tr.setBlock(i + 1)
+		encryptedChunk, err := encryptElGamal(key, chunk, tr)
 		if err != nil {
 			return "", err
 		}
 		encryptedChunks = append(encryptedChunks, encryptedChunk)
 	}
 
-	return strings.Join(encryptedChunks, "|"), nil
+	// Bản mã của khóa thuộc nhóm chuẩn được gắn tên nhóm: "<nhóm>:<c1,c2>|<c1,c2>..."
+	encryptedMessage := strings.Join(encryptedChunks, "|")
+	if key.Group != "" {
+		encryptedMessage = key.Group + ":" + encryptedMessage
+	}
+	return encryptedMessage, nil
 }
 
 // Giải mã ElGamal cho thông điệp dài
-func decryptElGamalLong(encryptedMessage string) (string, error) {
+func decryptElGamalLong(key *ElGamalKey, encryptedMessage string, tr *Trace) (string, error) {
+	// Kiểm tra bản mã được tạo trong đúng nhóm của khóa
+	group := ""
+	if tag, rest, found := strings.Cut(encryptedMessage, ":"); found {
+		group, encryptedMessage = tag, rest
+	}
+	if group != key.Group {
+		return "", fmt.Errorf("bản mã thuộc nhóm %q nhưng khóa thuộc nhóm %q", group, key.Group)
+	}
+
 	encryptedChunks := strings.Split(encryptedMessage, "|")
 	decryptedMessage := ""
 
-	for _, encryptedChunk := range encryptedChunks {
-		decryptedChunk, err := decryptElGamal(encryptedChunk)
+	for i, encryptedChunk := range encryptedChunks {
+		tr.setBlock(i + 1)
+		decryptedChunk, err := decryptElGamal(key, encryptedChunk, tr)
 		if err != nil {
 			return "", err
 		}
@@ -90,79 +198,190 @@ func decryptElGamalLong(encryptedMessage string) (string, error) {
 }
 
 // Mã hóa ElGamal
-func encryptElGamal(message string) (string, error) {
+func encryptElGamal(key *ElGamalKey, message string, tr *Trace) (string, error) {
 	msgInt := new(big.Int).SetBytes([]byte(message))
-	if msgInt.Cmp(p) >= 0 {
+	if msgInt.Cmp(key.P) >= 0 {
 		return "", fmt.Errorf("message quá lớn")
 	}
 
-	k, _ := rand.Int(rand.Reader, new(big.Int).Sub(p, big.NewInt(2)))
+	k, _ := rand.Int(rand.Reader, new(big.Int).Sub(key.P, big.NewInt(2)))
 	k.Add(k, big.NewInt(1))
-	c1 := new(big.Int).Exp(g, k, p)
-	s := new(big.Int).Exp(y, k, p)
+	c1 := new(big.Int).Exp(key.G, k, key.P)
+	s := new(big.Int).Exp(key.Y, k, key.P)
 	c2 := new(big.Int).Mul(msgInt, s)
-	c2.Mod(c2, p)
+	c2.Mod(c2, key.P)
+
+	tr.add("m", msgInt)
+	tr.add("k", k)
+	tr.add("c1 = g^k mod p", c1)
+	tr.add("s = y^k mod p", s)
+	tr.add("c2 = m*s mod p", c2)
 
 	return fmt.Sprintf("%x,%x", c1, c2), nil
 }
 
 // Giải mã ElGamal
-func decryptElGamal(encryptedMessage string) (string, error) {
+func decryptElGamal(key *ElGamalKey, encryptedMessage string, tr *Trace) (string, error) {
 	parts := strings.Split(encryptedMessage, ",")
 	if len(parts) != 2 {
 		return "", fmt.Errorf("sai định dạng bản mã")
 	}
 
-	c1, _ := new(big.Int).SetString(parts[0], 16)
-	c2, _ := new(big.Int).SetString(parts[1], 16)
+	c1, ok1 := new(big.Int).SetString(parts[0], 16)
+	c2, ok2 := new(big.Int).SetString(parts[1], 16)
+	if !ok1 || !ok2 {
+		return "", fmt.Errorf("sai định dạng bản mã")
+	}
 
-	s := new(big.Int).Exp(c1, x, p)
-	sInv := new(big.Int).ModInverse(s, p)
+	// c1 = g^k thuộc [1, p-1], c2 = m*s mod p thuộc [0, p-1]
+	if c1.Sign() <= 0 || c1.Cmp(key.P) >= 0 || c2.Sign() < 0 || c2.Cmp(key.P) >= 0 {
+		return "", fmt.Errorf("bản mã nằm ngoài khoảng [0, p-1]")
+	}
+
+	s := new(big.Int).Exp(c1, key.X, key.P)
+	sInv := new(big.Int).ModInverse(s, key.P)
 
 	msgInt := new(big.Int).Mul(c2, sInv)
-	msgInt.Mod(msgInt, p)
+	msgInt.Mod(msgInt, key.P)
+
+	tr.add("c1", c1)
+	tr.add("c2", c2)
+	tr.add("s = c1^x mod p", s)
+	tr.add("s^-1 mod p", sInv)
+	tr.add("m = c2*s^-1 mod p", msgInt)
 
 	return string(msgInt.Bytes()), nil
 }
 
-// Tạo chữ ký ElGamal
-func signElGamal(message string) (string, error) {
-	msgInt := new(big.Int).SetBytes([]byte(message))
+// Giá trị được ký theo lược đồ: textbook dùng thẳng m, hashed dùng H(m) = SHA-256(m) mod (p-1)
+func elGamalMessageInt(key *ElGamalKey, message, scheme string) *big.Int {
+	if scheme == ElGamalSchemeTextbook {
+		return new(big.Int).SetBytes([]byte(message))
+	}
+	hash := sha256.Sum256([]byte(message))
+	h := new(big.Int).SetBytes(hash[:])
+	return h.Mod(h, new(big.Int).Sub(key.P, big.NewInt(1)))
+}
 
-	k, _ := rand.Int(rand.Reader, new(big.Int).Sub(p, big.NewInt(2)))
-	k.Add(k, big.NewInt(1))
+// Tạo chữ ký ElGamal theo lược đồ đã chọn, dạng "scheme:r,s" (hex).
+// nonce chọn k ngẫu nhiên hoặc xác định theo RFC 6979 (với bậc p-1 hoặc q tùy lược đồ).
+func signElGamal(key *ElGamalKey, message, scheme, nonce string, tr *Trace) (string, error) {
+	var r, s *big.Int
+	var err error
+	if scheme == ElGamalSchemeSubgroup {
+		r, s, err = signElGamalSubgroup(key, message, nonce, tr)
+	} else {
+		r, s, err = signElGamalModP(key, message, scheme, nonce, tr)
+	}
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s:%x,%x", scheme, r, s), nil
+}
+
+// Chữ ký ElGamal gốc: r = g^k mod p, s = (h - x*r)*k^-1 mod (p-1) với gcd(k, p-1) = 1
+func signElGamalModP(key *ElGamalKey, message, scheme, nonce string, tr *Trace) (*big.Int, *big.Int, error) {
+	pMinus1 := new(big.Int).Sub(key.P, big.NewInt(1))
+	h := elGamalMessageInt(key, message, scheme)
+	if scheme == ElGamalSchemeTextbook {
+		// Không rút gọn ngầm thông điệp dài: hai thông điệp khác nhau sẽ có cùng chữ ký
+		if h.Cmp(pMinus1) >= 0 {
+			return nil, nil, fmt.Errorf("thông điệp quá dài cho ElGamal textbook (m phải nhỏ hơn p-1)")
+		}
+		tr.add("m", h)
+	} else {
+		tr.add("h = SHA-256(m) mod (p-1)", h)
+	}
 
-	r := new(big.Int).Exp(g, k, p)
+	hash := sha256.Sum256([]byte(message))
+	nextK := newNonceFunc(nonce, pMinus1, key.X, crypto.SHA256, hash[:])
+	one := big.NewInt(1)
+	for {
+		// k trong [1, p-2], phải nguyên tố cùng nhau với p-1 để có k^-1
+		k, err := nextK()
+		if err != nil {
+			return nil, nil, err
+		}
+		if new(big.Int).GCD(nil, nil, k, pMinus1).Cmp(one) != 0 {
+			continue
+		}
+		r := new(big.Int).Exp(key.G, k, key.P)
+		kInv := new(big.Int).ModInverse(k, pMinus1)
+		s := new(big.Int).Sub(h, new(big.Int).Mul(key.X, r))
+		s.Mul(s, kInv).Mod(s, pMinus1)
+		if s.Sign() == 0 {
+			continue
+		}
 
-	h := new(big.Int).Set(msgInt)
-	s := new(big.Int).Sub(h, new(big.Int).Mul(x, r))
-	s.Mod(s, new(big.Int).Sub(p, big.NewInt(1)))
-	sInv := new(big.Int).ModInverse(k, new(big.Int).Sub(p, big.NewInt(1)))
-	s.Mul(s, sInv)
-	s.Mod(s, new(big.Int).Sub(p, big.NewInt(1)))
+		tr.add("r = g^k mod p", r)
+		if scheme == ElGamalSchemeTextbook {
+			tr.add("s = (m - x*r)*k^-1 mod (p-1)", s)
+		} else {
+			tr.add("s = (h - x*r)*k^-1 mod (p-1)", s)
+		}
+		return r, s, nil
+	}
+}
 
-	return fmt.Sprintf("%x,%x", r, s), nil
+// Chữ ký kiểu DSA trong nhóm con bậc q: r = (g^k mod p) mod q, s = k^-1 (h + x*r) mod q,
+// h là SHA-256(m) cắt còn số bit của q
+func signElGamalSubgroup(key *ElGamalKey, message, nonce string, tr *Trace) (*big.Int, *big.Int, error) {
+	if key.Q == nil {
+		return nil, nil, fmt.Errorf("khóa ElGamal không có bậc nhóm con q")
+	}
+	h := hashToScalar(message, key.Q)
+	tr.add("h = SHA-256(m) (cắt theo q)", h)
+	hash := sha256.Sum256([]byte(message))
+	nextK := newNonceFunc(nonce, key.Q, key.X, crypto.SHA256, hash[:])
+	return signSubgroup(key.P, key.Q, key.G, key.X, h, nextK, tr)
 }
 
-// Xác minh chữ ký ElGamal
-func verifyElGamal(message string, signature string) (bool, error) {
-	parts := strings.Split(signature, ",")
-	if len(parts) != 2 {
-		return false, fmt.Errorf("sai định dạng chữ ký")
+// Xác minh chữ ký ElGamal; lược đồ đọc từ tiền tố của chữ ký
+func verifyElGamal(key *ElGamalKey, message string, signature string, tr *Trace) (bool, error) {
+	scheme, r, s, err := splitElGamalSignature(signature)
+	if err != nil {
+		return false, err
 	}
+	tr.add("r", r)
+	tr.add("s", s)
 
-	r, _ := new(big.Int).SetString(parts[0], 16)
-	s, _ := new(big.Int).SetString(parts[1], 16)
+	if scheme == ElGamalSchemeSubgroup {
+		return verifyElGamalSubgroup(key, message, r, s, tr)
+	}
 
-	msgInt := new(big.Int).SetBytes([]byte(message))
+	// 0 < r < p và 0 < s < p-1, nếu không r có thể được chọn để giả mạo chữ ký
+	pMinus1 := new(big.Int).Sub(key.P, big.NewInt(1))
+	if !inOpenRange(r, key.P) || !inOpenRange(s, pMinus1) {
+		return false, nil
+	}
 
-	v1 := new(big.Int).Exp(g, msgInt, p)
-	v2 := new(big.Int).Mul(new(big.Int).Exp(y, r, p), new(big.Int).Exp(r, s, p))
-	v2.Mod(v2, p)
+	h := elGamalMessageInt(key, message, scheme)
+	v1 := new(big.Int).Exp(key.G, h, key.P)
+	v2 := new(big.Int).Mul(new(big.Int).Exp(key.Y, r, key.P), new(big.Int).Exp(r, s, key.P))
+	v2.Mod(v2, key.P)
+
+	if scheme == ElGamalSchemeTextbook {
+		tr.add("m", h)
+		tr.add("v1 = g^m mod p", v1)
+	} else {
+		tr.add("h = SHA-256(m) mod (p-1)", h)
+		tr.add("v1 = g^h mod p", v1)
+	}
+	tr.add("v2 = y^r * r^s mod p", v2)
 
 	return v1.Cmp(v2) == 0, nil
 }
 
+// Xác minh chữ ký kiểu DSA trong nhóm con bậc q
+func verifyElGamalSubgroup(key *ElGamalKey, message string, r, s *big.Int, tr *Trace) (bool, error) {
+	if key.Q == nil {
+		return false, fmt.Errorf("khóa ElGamal không có bậc nhóm con q")
+	}
+	h := hashToScalar(message, key.Q)
+	tr.add("h = SHA-256(m) (cắt theo q)", h)
+	return verifySubgroup(key.P, key.Q, key.G, key.Y, h, r, s, tr), nil
+}
+
 // func main() {
 // 	// Tạo khóa ElGamal
 // 	generateElGamalKeys(512)
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func testElGamalKey(t *testing.T) *ElGamalKey {
+	t.Helper()
+	key, err := generateElGamalGroupKeys("modp2048")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestElGamalDecryptRejectsMalformedCiphertext(t *testing.T) {
+	key := testElGamalKey(t)
+	ciphertext, err := encryptElGamal(key, "Xin chào", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plaintext, err := decryptElGamal(key, ciphertext, nil); err != nil || plaintext != "Xin chào" {
+		t.Fatalf("giải mã %q, %v", plaintext, err)
+	}
+
+	for name, malformed := range map[string]string{
+		"c1 không phải hex": "zz,01",
+		"c2 không phải hex": "01,zz",
+		"c1 rỗng":           ",01",
+		"c1 = 0":            "0,01",
+		"c1 = p":            fmt.Sprintf("%x,01", key.P),
+		"c2 = p":            fmt.Sprintf("02,%x", key.P),
+		"c1 âm":             "-2,01",
+		"thiếu c2":          "01",
+	} {
+		if _, err := decryptElGamal(key, malformed, nil); err == nil {
+			t.Errorf("%s: chấp nhận bản mã sai", name)
+		}
+	}
+}
+
+func TestElGamalSignVerifyRoundTrip(t *testing.T) {
+	key := testElGamalKey(t)
+	message := "xin chào ElGamal"
+
+	for _, scheme := range []string{ElGamalSchemeHashed, ElGamalSchemeSubgroup, ElGamalSchemeTextbook} {
+		for _, nonce := range []string{NonceRandom, NonceRFC6979} {
+			signature, err := signElGamal(key, message, scheme, nonce, nil)
+			if err != nil {
+				t.Fatalf("%s/%s: %v", scheme, nonce, err)
+			}
+			if !strings.HasPrefix(signature, scheme+":") {
+				t.Fatalf("%s/%s: chữ ký thiếu tiền tố lược đồ: %s", scheme, nonce, signature)
+			}
+			if ok, err := verifyElGamal(key, message, signature, newTrace(true)); !ok || err != nil {
+				t.Fatalf("%s/%s: chữ ký hợp lệ bị từ chối: %v", scheme, nonce, err)
+			}
+		}
+
+		// RFC 6979 cho chữ ký xác định
+		s1, _ := signElGamal(key, message, scheme, NonceRFC6979, nil)
+		s2, _ := signElGamal(key, message, scheme, NonceRFC6979, nil)
+		if s1 != s2 {
+			t.Fatalf("%s: chữ ký RFC 6979 không tái lập được", scheme)
+		}
+	}
+
+	// Textbook không rút gọn ngầm thông điệp lớn hơn p-1
+	long := string(new(big.Int).Sub(key.P, big.NewInt(1)).Bytes())
+	if _, err := signElGamal(key, long, ElGamalSchemeTextbook, NonceRandom, nil); err == nil {
+		t.Error("textbook ký được thông điệp m >= p-1")
+	}
+
+	// Khóa cũ không có q không ký được theo nhóm con
+	legacy := *key
+	legacy.Q = nil
+	if _, err := signElGamal(&legacy, message, ElGamalSchemeSubgroup, NonceRandom, nil); err == nil {
+		t.Error("ký nhóm con với khóa không có q")
+	}
+}
+
+func TestElGamalVerifyRejectsTampering(t *testing.T) {
+	key := testElGamalKey(t)
+	message := "xin chào ElGamal"
+	one := big.NewInt(1)
+	pMinus1 := new(big.Int).Sub(key.P, one)
+
+	bounds := map[string][2]*big.Int{
+		ElGamalSchemeHashed:   {key.P, pMinus1},
+		ElGamalSchemeSubgroup: {key.Q, key.Q},
+		ElGamalSchemeTextbook: {key.P, pMinus1},
+	}
+	for scheme, bound := range bounds {
+		signature, err := signElGamal(key, message, scheme, NonceRandom, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, r, s, err := splitElGamalSignature(signature)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pair := func(r, s *big.Int) string { return fmt.Sprintf("%s:%x,%x", scheme, r, s) }
+
+		cases := map[string]string{
+			"r + 1":    pair(new(big.Int).Add(r, one), s),
+			"s + 1":    pair(r, new(big.Int).Add(s, one)),
+			"r = 0":    pair(new(big.Int), s),
+			"s = 0":    pair(r, new(big.Int)),
+			"r = cận":  pair(bound[0], s),
+			"s = cận":  pair(r, bound[1]),
+			"r + cận":  pair(new(big.Int).Add(r, bound[0]), s),
+			"s + cận":  pair(r, new(big.Int).Add(s, bound[1])),
+			"đổi r, s": pair(s, r),
+			"r âm":     fmt.Sprintf("%s:-%x,%x", scheme, r, s),
+		}
+		for name, sig := range cases {
+			if ok, _ := verifyElGamal(key, message, sig, nil); ok {
+				t.Errorf("%s/%s: chữ ký bị sửa vẫn hợp lệ", scheme, name)
+			}
+		}
+		if ok, _ := verifyElGamal(key, message+".", signature, nil); ok {
+			t.Errorf("%s: chấp nhận chữ ký cho thông điệp khác", scheme)
+		}
+		if ok, _ := verifyElGamal(testElGamalKey(t), message, signature, nil); ok {
+			t.Errorf("%s: chấp nhận chữ ký với khóa khác", scheme)
+		}
+	}
+
+	// Đổi tiền tố lược đồ thì chữ ký không còn hợp lệ
+	hashed, _ := signElGamal(key, message, ElGamalSchemeHashed, NonceRandom, nil)
+	subgroup, _ := signElGamal(key, message, ElGamalSchemeSubgroup, NonceRandom, nil)
+	for _, sig := range []string{
+		strings.Replace(hashed, ElGamalSchemeHashed, ElGamalSchemeTextbook, 1),
+		strings.TrimPrefix(hashed, ElGamalSchemeHashed+":"),
+		strings.Replace(subgroup, ElGamalSchemeSubgroup, ElGamalSchemeHashed, 1),
+	} {
+		if ok, _ := verifyElGamal(key, message, sig, nil); ok {
+			t.Errorf("chấp nhận chữ ký đổi lược đồ %q", sig[:12])
+		}
+	}
+
+	for _, sig := range []string{"", "abc", "hashed:1,2,3", "hashed:xyz,1", "dsa:1,2", ":1,2"} {
+		if _, err := verifyElGamal(key, message, sig, nil); err == nil {
+			t.Errorf("chữ ký %q sai định dạng không báo lỗi", sig)
+		}
+	}
+}
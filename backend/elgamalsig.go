@@ -0,0 +1,53 @@
+// elgamalsig.go
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Lược đồ chữ ký ElGamal
+const (
+	ElGamalSchemeHashed   = "hashed"   // ký H(m) = SHA-256(m) mod (p-1) (mặc định)
+	ElGamalSchemeSubgroup = "subgroup" // kiểu DSA trong nhóm con bậc q: r = (g^k mod p) mod q
+	ElGamalSchemeTextbook = "textbook" // ký trực tiếp m không băm: KHÔNG an toàn, chỉ dùng để giảng dạy
+)
+
+// Cảnh báo trả về kèm chữ ký theo lược đồ textbook
+const elGamalTextbookWarning = "ElGamal textbook ký trực tiếp thông điệp không băm nên giả mạo được dễ dàng, chỉ dùng để giảng dạy"
+
+// Chuẩn hóa lược đồ chữ ký ElGamal trong yêu cầu ký
+func parseElGamalScheme(scheme string) (string, error) {
+	switch s := strings.ToLower(scheme); s {
+	case "":
+		return ElGamalSchemeHashed, nil
+	case ElGamalSchemeHashed, ElGamalSchemeSubgroup, ElGamalSchemeTextbook:
+		return s, nil
+	}
+	return "", fmt.Errorf("lược đồ chữ ký ElGamal không được hỗ trợ: %s", scheme)
+}
+
+// Tách chữ ký "scheme:r,s" thành lược đồ, r và s.
+// Chữ ký cũ không có tiền tố được coi là textbook.
+func splitElGamalSignature(signature string) (string, *big.Int, *big.Int, error) {
+	scheme := ElGamalSchemeTextbook
+	if prefix, rest, ok := strings.Cut(signature, ":"); ok {
+		var err error
+		if scheme, err = parseElGamalScheme(prefix); err != nil || prefix == "" {
+			return "", nil, nil, fmt.Errorf("sai định dạng chữ ký")
+		}
+		signature = rest
+	}
+
+	r, s, err := decodeHexSignaturePair(signature)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	return scheme, r, s, nil
+}
+
+// Kiểm tra 0 < x < bound
+func inOpenRange(x, bound *big.Int) bool {
+	return x.Sign() > 0 && x.Cmp(bound) < 0
+}
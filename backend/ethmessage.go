@@ -0,0 +1,59 @@
+// ethmessage.go
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// Thông điệp kiểu ví Ethereum ký bằng khóa secp256k1:
+// EIP191 (personal_sign) và EIP712 (dữ liệu có cấu trúc), xác thực theo địa chỉ người ký
+var ethereumMessageAlgorithms = map[string]bool{"EIP191": true, "EIP712": true}
+
+// Giá trị băm được ký.
+// EIP191: keccak256("\x19Ethereum Signed Message:\n" || len(m) || m).
+// EIP712: keccak256("\x19\x01" || domainSeparator || hashStruct(message)), typed data lấy từ typedData
+// hoặc từ message khi typedData rỗng.
+func ethereumMessageHash(algorithm, message string, typedData json.RawMessage) ([]byte, error) {
+	switch algorithm {
+	case "EIP191":
+		return accounts.TextHash([]byte(message)), nil
+	case "EIP712":
+		if len(typedData) == 0 {
+			typedData = json.RawMessage(message)
+		}
+		var data apitypes.TypedData
+		if err := json.Unmarshal(typedData, &data); err != nil {
+			return nil, fmt.Errorf("typedData không hợp lệ: %v", err)
+		}
+		hash, _, err := apitypes.TypedDataAndHash(data)
+		if err != nil {
+			return nil, fmt.Errorf("typedData không hợp lệ: %v", err)
+		}
+		return hash, nil
+	}
+	return nil, fmt.Errorf("thuật toán không được hỗ trợ: %s", algorithm)
+}
+
+// Xác thực thông điệp kiểu ví: địa chỉ khôi phục từ chữ ký phải trùng address.
+// Trả lỗi khi address hoặc typed data không hợp lệ, (false, nil) khi chữ ký sai.
+func verifyEthereumMessage(address, algorithm, message string, typedData json.RawMessage, signature string) (bool, error) {
+	if !common.IsHexAddress(address) {
+		return false, errors.New("địa chỉ Ethereum không hợp lệ")
+	}
+	hash, err := ethereumMessageHash(algorithm, message, typedData)
+	if err != nil {
+		return false, err
+	}
+	publicKey, err := recoverSecp256k1(hash, signature)
+	if err != nil {
+		return false, nil
+	}
+	return ethcrypto.PubkeyToAddress(*publicKey) == common.HexToAddress(address), nil
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+)
+
+// Ví dụ "Mail" trong đặc tả EIP-712
+const eip712MailExample = `{
+	"types": {
+		"EIP712Domain": [
+			{"name": "name", "type": "string"},
+			{"name": "version", "type": "string"},
+			{"name": "chainId", "type": "uint256"},
+			{"name": "verifyingContract", "type": "address"}
+		],
+		"Person": [
+			{"name": "name", "type": "string"},
+			{"name": "wallet", "type": "address"}
+		],
+		"Mail": [
+			{"name": "from", "type": "Person"},
+			{"name": "to", "type": "Person"},
+			{"name": "contents", "type": "string"}
+		]
+	},
+	"primaryType": "Mail",
+	"domain": {
+		"name": "Ether Mail",
+		"version": "1",
+		"chainId": 1,
+		"verifyingContract": "0xCcCCccccCCCCcCCCCCCcCcCccCcCCCcCcccccccC"
+	},
+	"message": {
+		"from": {"name": "Cow", "wallet": "0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826"},
+		"to": {"name": "Bob", "wallet": "0xbBbBBBBbbBBBbbbBbbBbbbbBBbBbbbbBbBbbBBbB"},
+		"contents": "Hello, Bob!"
+	}
+}`
+
+func TestEIP712MailExampleHash(t *testing.T) {
+	hash, err := ethereumMessageHash("EIP712", "", json.RawMessage(eip712MailExample))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := hex.EncodeToString(hash), "be609aee343fb3c4b28e1df9e632fca64fcfaede20f02e86244efddf30957bd2"; got != want {
+		t.Fatalf("hash %s, cần %s", got, want)
+	}
+
+	// typed data cũng đọc được từ message khi typedData rỗng
+	fromMessage, err := ethereumMessageHash("EIP712", eip712MailExample, nil)
+	if err != nil || hex.EncodeToString(fromMessage) != hex.EncodeToString(hash) {
+		t.Fatalf("hash từ message khác: %x, %v", fromMessage, err)
+	}
+}
+
+func TestEIP712MailExampleSignature(t *testing.T) {
+	// Người ký trong đặc tả: khóa bí mật keccak256("cow")
+	priv, err := ethcrypto.ToECDSA(ethcrypto.Keccak256([]byte("cow")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := ethereumAddress(&priv.PublicKey)
+	if address != "0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826" {
+		t.Fatalf("địa chỉ người ký %s", address)
+	}
+
+	hash, err := ethereumMessageHash("EIP712", "", json.RawMessage(eip712MailExample))
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature, err := signSecp256k1Hash(priv, hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// r, s, v trong đặc tả (chữ ký xác định theo RFC 6979)
+	want := "0x4355c47d63924e8a72e509b65029052eb6c299d53a04e167c5775fd466751c9d" +
+		"07299936d304c153f6443dfa05f40ff007d72911b6f72307f996231605b91562" + "1c"
+	if signature != want {
+		t.Fatalf("chữ ký %s, cần %s", signature, want)
+	}
+
+	if ok, err := verifyEthereumMessage(address, "EIP712", "", json.RawMessage(eip712MailExample), signature); !ok || err != nil {
+		t.Fatalf("chữ ký hợp lệ bị từ chối: %v", err)
+	}
+	other := "0xbBbBBBBbbBBBbbbBbbBbbbbBBbBbbbbBbBbbBBbB"
+	if ok, _ := verifyEthereumMessage(other, "EIP712", "", json.RawMessage(eip712MailExample), signature); ok {
+		t.Fatal("chấp nhận chữ ký với địa chỉ khác")
+	}
+}
+
+func TestEIP191PersonalSign(t *testing.T) {
+	hash, err := ethereumMessageHash("EIP191", "Hello World", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := hex.EncodeToString(hash), "a1de988600a42c4b4ab089b619297c17d53cffae5d5120d82d8a92d0bb3b78f2"; got != want {
+		t.Fatalf("hash %s, cần %s", got, want)
+	}
+
+	priv, err := generateSecp256k1Key()
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature, err := signSecp256k1Hash(priv, hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := ethereumAddress(&priv.PublicKey)
+	if ok, err := verifyEthereumMessage(address, "EIP191", "Hello World", nil, signature); !ok || err != nil {
+		t.Fatalf("chữ ký hợp lệ bị từ chối: %v", err)
+	}
+	if ok, _ := verifyEthereumMessage(address, "EIP191", "Hello World!", nil, signature); ok {
+		t.Fatal("chấp nhận chữ ký cho thông điệp khác")
+	}
+	if _, err := verifyEthereumMessage("0x1234", "EIP191", "Hello World", nil, signature); err == nil {
+		t.Fatal("chấp nhận địa chỉ không hợp lệ")
+	}
+}
@@ -1,5 +1,15 @@
 module backend
 
-go 1.23.3
+go 1.24.0
 
-require github.com/ethereum/go-ethereum v1.11.6
+require (
+	github.com/ethereum/go-ethereum v1.11.6
+	golang.org/x/crypto v0.31.0
+)
+
+require (
+	github.com/btcsuite/btcd/btcec/v2 v2.2.0 // indirect
+	github.com/decred/dcrd/dcrec/secp256k1/v4 v4.0.1 // indirect
+	github.com/holiman/uint256 v1.2.2-0.20230321075855-87b91420868c // indirect
+	golang.org/x/sys v0.28.0 // indirect
+)
@@ -0,0 +1,219 @@
+// jwk.go
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+)
+
+// Khóa công khai dạng JWK (RFC 7517)
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+
+	// EC và OKP (Ed25519, RFC 8037)
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// Tập khóa JWKS
+type JWKSet struct {
+	Keys []*JWK `json:"keys"`
+}
+
+var b64url = base64.RawURLEncoding
+
+// Thuật toán JOSE tương ứng với chữ ký /sign tạo ra với format jws cho mỗi đường cong
+var ecdsaJWSAlgorithms = map[string]string{
+	"P-256": "ES256",
+	"P-384": "ES384",
+	"P-521": "ES512",
+}
+
+// Định dạng chữ ký JWS (RFC 7515) của /sign: base64url không đệm, không có tiền tố
+// phiên bản, xác thực bằng JWK có kid ghi trong phản hồi
+const SignatureFormatJWS = "jws"
+
+// Tạo JWK cho phần công khai của một phiên bản khóa RSA, ECDSA, Ed25519 hoặc secp256k1.
+// Mỗi phiên bản có kid riêng nên JWKS giữ được khóa cũ sau khi xoay vòng.
+func publicJWK(keyType string, kv *KeyVersion) (*JWK, error) {
+	var jwk *JWK
+	switch keyType {
+	case KeyTypeRSA:
+		// Khóa RSA ký được bằng PKCS#1 v1.5 hoặc PSS với nhiều hàm băm nên không ghi alg
+		jwk = rsaPublicJWK(&kv.RSA.PublicKey)
+	case KeyTypeECDSA:
+		var err error
+		if jwk, err = ecdsaPublicJWK(&kv.ECDSA.PublicKey); err != nil {
+			return nil, err
+		}
+		jwk.Alg = ecdsaJWSAlgorithms[jwk.Crv]
+	case KeyTypeEd25519:
+		jwk = &JWK{
+			Kty: "OKP",
+			Crv: "Ed25519",
+			X:   b64url.EncodeToString(kv.Ed25519.Public().(ed25519.PublicKey)),
+			Alg: "EdDSA",
+		}
+	case KeyTypeSecp256k1:
+		// RFC 8812: khóa secp256k1 ký JWS theo ES256K
+		jwk = &JWK{
+			Kty: "EC",
+			Crv: "secp256k1",
+			X:   b64url.EncodeToString(kv.Secp256k1.X.FillBytes(make([]byte, 32))),
+			Y:   b64url.EncodeToString(kv.Secp256k1.Y.FillBytes(make([]byte, 32))),
+			Alg: "ES256K",
+		}
+	default:
+		return nil, fmt.Errorf("không hỗ trợ JWK cho khóa %s", keyType)
+	}
+
+	jwk.Use = "sig"
+	jwk.Kid = jwkThumbprint(jwk)
+	return jwk, nil
+}
+
+func rsaPublicJWK(pub *rsa.PublicKey) *JWK {
+	return &JWK{
+		Kty: "RSA",
+		N:   b64url.EncodeToString(pub.N.Bytes()),
+		E:   b64url.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+	}
+}
+
+func ecdsaPublicJWK(pub *ecdsa.PublicKey) (*JWK, error) {
+	x, y, err := ecdsaPublicPoint(pub)
+	if err != nil {
+		return nil, err
+	}
+	return &JWK{
+		Kty: "EC",
+		Crv: pub.Curve.Params().Name,
+		X:   b64url.EncodeToString(x),
+		Y:   b64url.EncodeToString(y),
+	}, nil
+}
+
+// Thumbprint RFC 7638 dùng làm kid: SHA-256 của các trường bắt buộc theo thứ tự từ điển
+func jwkThumbprint(jwk *JWK) string {
+	var canonical string
+	switch jwk.Kty {
+	case "RSA":
+		canonical = fmt.Sprintf(`{"e":%q,"kty":"RSA","n":%q}`, jwk.E, jwk.N)
+	case "EC":
+		canonical = fmt.Sprintf(`{"crv":%q,"kty":"EC","x":%q,"y":%q}`, jwk.Crv, jwk.X, jwk.Y)
+	case "OKP":
+		canonical = fmt.Sprintf(`{"crv":%q,"kty":"OKP","x":%q}`, jwk.Crv, jwk.X)
+	}
+	sum := sha256.Sum256([]byte(canonical))
+	return b64url.EncodeToString(sum[:])
+}
+
+// Ký thông điệp (JWS signing input "header.payload") theo alg trong JWK của khóa:
+// ES256/ES384/ES512 cho ECDSA, EdDSA cho Ed25519 thuần, ES256K cho secp256k1.
+// Chữ ký ECDSA là r || s độ dài cố định (RFC 7518 mục 3.4).
+func signJWS(algorithm string, kv *KeyVersion, message, nonce string) (string, error) {
+	var signature []byte
+	switch algorithm {
+	case "ECC", "ECDSA":
+		r, s, err := signECDSA(kv.ECDSA, message, nonce)
+		if err != nil {
+			return "", err
+		}
+		size := ecdsaScalarSize(&kv.ECDSA.PublicKey)
+		signature = make([]byte, 2*size)
+		r.FillBytes(signature[:size])
+		s.FillBytes(signature[size:])
+	case "ED25519":
+		signature = ed25519.Sign(kv.Ed25519, []byte(message))
+	case "SECP256K1":
+		// ES256K băm bằng SHA-256, bỏ byte khôi phục v của chữ ký Ethereum
+		digest := sha256.Sum256([]byte(message))
+		sig, err := ethcrypto.Sign(digest[:], kv.Secp256k1)
+		if err != nil {
+			return "", err
+		}
+		signature = sig[:64]
+	default:
+		return "", errors.New("định dạng jws chỉ dùng cho ECDSA, ED25519 và SECP256K1")
+	}
+	return b64url.EncodeToString(signature), nil
+}
+
+// Kid của một phiên bản khóa, rỗng nếu khóa không có dạng JWK
+func keyKid(keyType string, kv *KeyVersion) string {
+	jwk, err := publicJWK(keyType, kv)
+	if err != nil {
+		return ""
+	}
+	return jwk.Kid
+}
+
+// GET /.well-known/jwks.json: khóa công khai của mọi phiên bản của các khóa ký đang hoạt động
+func jwksHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	set := JWKSet{Keys: []*JWK{}}
+	for _, key := range registry.list() {
+		if registry.isDisabled(key) {
+			continue
+		}
+		for _, kv := range key.allVersions() {
+			jwk, err := publicJWK(key.Type, kv)
+			if err != nil {
+				continue
+			}
+			set.Keys = append(set.Keys, jwk)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/jwk-set+json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	json.NewEncoder(w).Encode(set)
+}
+
+// GET /keys/{id}/jwk?version=N: JWK của một khóa (mặc định phiên bản mới nhất)
+func keyJWKHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	key, ok := registry.get(r.PathValue("id"))
+	if !ok {
+		http.Error(w, "Key not found", http.StatusNotFound)
+		return
+	}
+	kv, err := requestedKeyVersion(key, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	jwk, err := publicJWK(key.Type, kv)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/jwk+json")
+	json.NewEncoder(w).Encode(jwk)
+}
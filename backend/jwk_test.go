@@ -0,0 +1,111 @@
+package main
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"math/big"
+	"net/http"
+	"strings"
+	"testing"
+
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+)
+
+// Xác thực chữ ký JWS bằng JWK theo cách của công cụ JOSE: chỉ dùng kty/crv/alg và x/y
+func verifyJWSWithJWK(t *testing.T, jwk *JWK, signingInput, signature string) bool {
+	t.Helper()
+	sig, err := b64url.DecodeString(signature)
+	if err != nil {
+		t.Fatalf("chữ ký không phải base64url: %v", err)
+	}
+	x, err := b64url.DecodeString(jwk.X)
+	if err != nil {
+		t.Fatal(err)
+	}
+	y, _ := b64url.DecodeString(jwk.Y)
+
+	switch jwk.Alg {
+	case "ES256", "ES384", "ES512":
+		curves := map[string]elliptic.Curve{"P-256": elliptic.P256(), "P-384": elliptic.P384(), "P-521": elliptic.P521()}
+		hashes := map[string]crypto.Hash{"ES256": crypto.SHA256, "ES384": crypto.SHA384, "ES512": crypto.SHA512}
+		pub := &ecdsa.PublicKey{Curve: curves[jwk.Crv], X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		size := len(sig) / 2
+		h := hashes[jwk.Alg].New()
+		h.Write([]byte(signingInput))
+		return len(x) == size && ecdsa.Verify(pub, h.Sum(nil), new(big.Int).SetBytes(sig[:size]), new(big.Int).SetBytes(sig[size:]))
+	case "ES256K":
+		digest := sha256.Sum256([]byte(signingInput))
+		point := append(append([]byte{0x04}, x...), y...)
+		return len(sig) == 64 && ethcrypto.VerifySignature(point, digest[:], sig)
+	case "EdDSA":
+		return ed25519.Verify(ed25519.PublicKey(x), []byte(signingInput), sig)
+	}
+	t.Fatalf("alg không được hỗ trợ: %q", jwk.Alg)
+	return false
+}
+
+func TestSignJWSVerifiesWithJWKS(t *testing.T) {
+	reg := useTestRegistry(t)
+	keys := []struct {
+		algorithm, keyType, curve, alg string
+	}{
+		{"ECDSA", KeyTypeECDSA, "P-256", "ES256"},
+		{"ECDSA", KeyTypeECDSA, "P-384", "ES384"},
+		{"ECDSA", KeyTypeECDSA, "P-521", "ES512"},
+		{"ED25519", KeyTypeEd25519, "", "EdDSA"},
+		{"SECP256K1", KeyTypeSecp256k1, "", "ES256K"},
+	}
+	for i, k := range keys {
+		if _, err := reg.generate(k.keyType, k.alg+"-"+k.curve, KeyOptions{Curve: k.curve}); err != nil {
+			t.Fatalf("%d: %v", i, err)
+		}
+	}
+	// Khóa không có dạng JWK không làm mất các khóa khác trong JWKS
+	if _, err := reg.generate(KeyTypeAES, "aes", KeyOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	var set JWKSet
+	if code := serveJSON(t, "/.well-known/jwks.json", jwksHandler, http.MethodGet, "/.well-known/jwks.json", nil, &set); code != http.StatusOK {
+		t.Fatalf("jwks: %d", code)
+	}
+	if len(set.Keys) != len(keys) {
+		t.Fatalf("JWKS có %d khóa, cần %d", len(set.Keys), len(keys))
+	}
+	byKid := map[string]*JWK{}
+	for _, jwk := range set.Keys {
+		byKid[jwk.Kid] = jwk
+	}
+
+	signingInput := "eyJhbGciOiJFUzI1NiJ9.eyJzdWIiOiJ0ZXN0In0"
+	for _, k := range keys {
+		name := k.alg
+		req := SignRequest{Algorithm: k.algorithm, KeyID: k.alg + "-" + k.curve, Message: signingInput, Format: "jws"}
+		var resp SignResponse
+		if code := serveJSON(t, "/sign", signHandler, http.MethodPost, "/sign", req, &resp); code != http.StatusOK {
+			t.Fatalf("%s: ký jws: %d", name, code)
+		}
+		if resp.Alg != k.alg || strings.HasPrefix(resp.Signature, "v1:") || strings.ContainsAny(resp.Signature, "+/=") {
+			t.Fatalf("%s: alg %q, chữ ký %q", name, resp.Alg, resp.Signature)
+		}
+		jwk, ok := byKid[resp.Kid]
+		if !ok || jwk.Alg != k.alg {
+			t.Fatalf("%s: không tìm thấy kid %q trong JWKS", name, resp.Kid)
+		}
+		if !verifyJWSWithJWK(t, jwk, signingInput, resp.Signature) {
+			t.Errorf("%s: chữ ký jws không hợp lệ với JWK", name)
+		}
+		if verifyJWSWithJWK(t, jwk, signingInput+"x", resp.Signature) {
+			t.Errorf("%s: chữ ký jws hợp lệ với thông điệp khác", name)
+		}
+	}
+
+	// Các thuật toán không có alg JWS thì không ký được dạng jws
+	req := SignRequest{Algorithm: "ED25519PH", KeyID: "EdDSA-", Message: signingInput, Format: "jws"}
+	if code := serveJSON(t, "/sign", signHandler, http.MethodPost, "/sign", req, nil); code != http.StatusBadRequest {
+		t.Fatalf("ED25519PH dạng jws: %d", code)
+	}
+}
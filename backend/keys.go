@@ -0,0 +1,892 @@
+// keys.go
+package main
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+)
+
+// Các loại khóa được registry quản lý
+const (
+	KeyTypeRSA       = "RSA"
+	KeyTypeElGamal   = "ELGAMAL"
+	KeyTypeECC       = "ECC"
+	KeyTypeECDSA     = "ECDSA"
+	KeyTypeECIES     = "ECIES"
+	KeyTypeEC        = "EC"
+	KeyTypeEd25519   = "ED25519"
+	KeyTypeSecp256k1 = "SECP256K1"
+	KeyTypeSchnorr   = "SCHNORR"
+	KeyTypeDSA       = "DSA"
+	KeyTypeAES       = "AES"
+	KeyTypeChaCha20  = "CHACHA20"
+)
+
+// Loại khóa dùng cho mỗi thuật toán mã hóa / giải mã
+var encryptionKeyTypes = map[string]string{
+	"RSA":         KeyTypeRSA,
+	"RSA-AES-GCM": KeyTypeRSA,
+	"ELGAMAL":     KeyTypeElGamal,
+	"ECC":         KeyTypeECC,
+	"ECIES":       KeyTypeECIES,
+	"EC-ELGAMAL":  KeyTypeEC,
+
+	// Mã hóa đối xứng có xác thực bằng khóa bí mật do server giữ
+	"AES-GCM":            KeyTypeAES,
+	"CHACHA20-POLY1305":  KeyTypeChaCha20,
+	"XCHACHA20-POLY1305": KeyTypeChaCha20,
+}
+
+// Loại khóa dùng cho mỗi thuật toán ký / xác thực
+var signatureKeyTypes = map[string]string{
+	"RSA":      KeyTypeRSA,
+	"ELGAMAL":  KeyTypeElGamal,
+	"ECC":      KeyTypeECDSA,
+	"ECDSA":    KeyTypeECDSA,
+	"EC-ECDSA": KeyTypeEC,
+
+	// Ed25519 thuần, Ed25519ph (băm trước bằng SHA-512) và Ed25519ctx (RFC 8032)
+	"ED25519":    KeyTypeEd25519,
+	"ED25519PH":  KeyTypeEd25519,
+	"ED25519CTX": KeyTypeEd25519,
+
+	// ECDSA trên secp256k1 với chữ ký khôi phục được (Ethereum),
+	// thông điệp kiểu ví EIP-191 (personal_sign) và EIP-712 (dữ liệu có cấu trúc)
+	"SECP256K1": KeyTypeSecp256k1,
+	"EIP191":    KeyTypeSecp256k1,
+	"EIP712":    KeyTypeSecp256k1,
+
+	// Schnorr BIP-340 trên secp256k1 (khóa công khai x-only), dùng khóa riêng
+	// vì phép nhân điểm tự cài đặt không chạy trong thời gian hằng
+	"SCHNORR": KeyTypeSchnorr,
+
+	// DSA theo FIPS 186-4
+	"DSA": KeyTypeDSA,
+}
+
+var keyIDPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]{0,63}$`)
+
+// Một khóa có tên trong registry, gồm nhiều phiên bản (cũ nhất trước)
+type Key struct {
+	ID        string
+	Type      string
+	CreatedAt time.Time
+
+	// Chỉ đọc/ghi khi giữ KeyRegistry.mu
+	Disabled bool
+
+	mu       sync.RWMutex
+	versions []*KeyVersion
+}
+
+// Một phiên bản của khóa, chỉ một trong các trường khóa được đặt theo loại khóa
+type KeyVersion struct {
+	Version   int
+	CreatedAt time.Time
+
+	RSA     *rsa.PrivateKey
+	ElGamal *ElGamalKey
+	ECC     *ECCKey
+	ECDSA   *ecdsa.PrivateKey
+	ECIES   *ecdh.PrivateKey
+	EC      *ECKey
+	Ed25519 ed25519.PrivateKey
+	DSA     *DSAKey
+
+	// Khóa secp256k1 (go-ethereum), không dùng chung trường ECDSA vì crypto/x509 không hỗ trợ đường cong này
+	Secp256k1 *ecdsa.PrivateKey
+
+	// Khóa secp256k1 riêng của Schnorr, không bao giờ dùng cho SECP256K1/EIP191/EIP712
+	Schnorr *ecdsa.PrivateKey
+
+	// Khóa bí mật đối xứng của khóa AES hoặc CHACHA20
+	Secret []byte
+
+	// Số thông điệp đã mã hóa với nonce 96 bit ngẫu nhiên và mức đã ghi xuống keystore
+	// (xem countAEADEncryption). Chỉ đọc/ghi khi giữ Key.mu
+	encryptions, encryptionsSaved uint64
+
+	// Hàm băm khi ký bằng khóa ECDSA (theo đường cong). Hash = 0 là phiên bản tạo trước khi
+	// ghi hàm băm, chữ ký dạng cũ của phiên bản này có thể đã được ký trên SHA-256.
+	Hash crypto.Hash
+}
+
+// Ghi lại hàm băm ký của phiên bản khóa ECDSA mới sinh hoặc mới nhập
+func (kv *KeyVersion) setSignatureHash() {
+	if kv.ECDSA != nil {
+		kv.Hash = ecdsaHash(&kv.ECDSA.PublicKey)
+	}
+}
+
+// Phiên bản mới nhất, dùng để mã hóa và ký
+func (key *Key) latest() *KeyVersion {
+	key.mu.RLock()
+	defer key.mu.RUnlock()
+
+	return key.versions[len(key.versions)-1]
+}
+
+// Tìm phiên bản theo số thứ tự
+func (key *Key) version(n int) (*KeyVersion, error) {
+	key.mu.RLock()
+	defer key.mu.RUnlock()
+
+	for _, kv := range key.versions {
+		if kv.Version == n {
+			return kv, nil
+		}
+	}
+	return nil, fmt.Errorf("khóa %q không có phiên bản %d", key.ID, n)
+}
+
+// Tất cả phiên bản của khóa
+func (key *Key) allVersions() []*KeyVersion {
+	key.mu.RLock()
+	defer key.mu.RUnlock()
+
+	return append([]*KeyVersion(nil), key.versions...)
+}
+
+// Gắn số phiên bản khóa vào đầu bản mã hoặc chữ ký: "v<N>:<dữ liệu>"
+func withKeyVersion(version int, data string) string {
+	return fmt.Sprintf("v%d:%s", version, data)
+}
+
+// Tách số phiên bản khỏi bản mã hoặc chữ ký.
+// Dữ liệu tạo trước khi có phiên bản khóa được coi là của phiên bản 1.
+func splitKeyVersion(data string) (int, string) {
+	prefix, rest, found := strings.Cut(data, ":")
+	if found && len(prefix) > 1 && prefix[0] == 'v' {
+		if n, err := strconv.Atoi(prefix[1:]); err == nil && n > 0 {
+			return n, rest
+		}
+	}
+	return 1, data
+}
+
+// Chọn phiên bản khóa ghi trong bản mã hoặc chữ ký
+func (key *Key) versionFor(data string) (*KeyVersion, string, error) {
+	n, rest := splitKeyVersion(data)
+	kv, err := key.version(n)
+	if err != nil {
+		return nil, "", err
+	}
+	return kv, rest, nil
+}
+
+// Tham số khi sinh khóa mới
+type KeyOptions struct {
+	Bits        int
+	QBits       int
+	Curve       string
+	Group       string
+	CurveParams *CurveParams
+}
+
+// Dữ liệu của một khóa khi lưu vào keystore
+type keyRecord struct {
+	ID        string             `json:"id"`
+	Type      string             `json:"type"`
+	CreatedAt time.Time          `json:"createdAt"`
+	Disabled  bool               `json:"disabled,omitempty"`
+	Versions  []keyVersionRecord `json:"versions"`
+
+	// Khóa lưu trước khi có phiên bản chỉ có một dữ liệu khóa
+	Material []byte `json:"material,omitempty"`
+}
+
+type keyVersionRecord struct {
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"createdAt"`
+	Material  []byte    `json:"material"`
+	Hash      string    `json:"hash,omitempty"`
+
+	// Số lần mã hóa AEAD với nonce 96 bit đã dành trước cho phiên bản
+	Encryptions uint64 `json:"encryptions,omitempty"`
+}
+
+// KeyRegistry quản lý nhiều khóa có tên cho mỗi thuật toán
+type KeyRegistry struct {
+	mu       sync.RWMutex
+	ks       *Keystore
+	keys     map[string]*Key
+	defaults map[string]string
+}
+
+var registry *KeyRegistry
+
+// Mở registry từ keystore, sinh khóa mặc định cho các loại chưa có khóa nào
+func openKeyRegistry(ks *Keystore) (*KeyRegistry, error) {
+	reg := &KeyRegistry{
+		ks:       ks,
+		keys:     map[string]*Key{},
+		defaults: map[string]string{},
+	}
+
+	names, err := ks.list()
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		key, err := reg.loadKey(name)
+		if err != nil {
+			return nil, fmt.Errorf("khóa %s: %v", name, err)
+		}
+		reg.keys[key.ID] = key
+	}
+
+	for _, keyType := range []string{KeyTypeRSA, KeyTypeElGamal, KeyTypeECC, KeyTypeECDSA, KeyTypeECIES, KeyTypeEC, KeyTypeEd25519, KeyTypeSecp256k1, KeyTypeSchnorr, KeyTypeDSA, KeyTypeAES, KeyTypeChaCha20} {
+		if len(reg.listByType(keyType)) == 0 {
+			if _, err := reg.generate(keyType, strings.ToLower(keyType), KeyOptions{}); err != nil {
+				return nil, fmt.Errorf("không thể sinh khóa %s: %v", keyType, err)
+			}
+		}
+
+		// Khóa mặc định: lấy từ DEFAULT_KEY_<TYPE>, nếu không có thì dùng khóa cũ nhất
+		id := getEnv("DEFAULT_KEY_"+keyType, "")
+		if id == "" {
+			id = reg.listByType(keyType)[0].ID
+		}
+		if err := reg.setDefault(keyType, id); err != nil {
+			return nil, err
+		}
+	}
+
+	return reg, nil
+}
+
+// Đọc một khóa từ keystore, chuyển khóa định dạng cũ sang định dạng mới
+func (reg *KeyRegistry) loadKey(name string) (*Key, error) {
+	entry, plaintext, err := reg.ks.load(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if entry.Version == 1 {
+		// Keystore phiên bản 1 chỉ lưu dữ liệu khóa, tên file chính là keyId
+		kv, err := parseKeyMaterial(entry.Type, plaintext)
+		if err != nil {
+			return nil, err
+		}
+		now := time.Now().UTC()
+		kv.Version, kv.CreatedAt = 1, now
+		key := &Key{ID: name, Type: entry.Type, CreatedAt: now, versions: []*KeyVersion{kv}}
+		return key, reg.saveKey(key)
+	}
+
+	var record keyRecord
+	if err := json.Unmarshal(plaintext, &record); err != nil {
+		return nil, err
+	}
+	if len(record.Versions) == 0 && record.Material != nil {
+		record.Versions = []keyVersionRecord{{Version: 1, CreatedAt: record.CreatedAt, Material: record.Material}}
+	}
+	if len(record.Versions) == 0 {
+		return nil, errors.New("khóa không có phiên bản nào")
+	}
+
+	key := &Key{ID: record.ID, Type: record.Type, CreatedAt: record.CreatedAt, Disabled: record.Disabled}
+	for _, vr := range record.Versions {
+		kv, err := parseKeyMaterial(record.Type, vr.Material)
+		if err != nil {
+			return nil, fmt.Errorf("phiên bản %d: %v", vr.Version, err)
+		}
+		kv.Version, kv.CreatedAt = vr.Version, vr.CreatedAt
+		if vr.Hash != "" {
+			hash, ok := rsaSignatureHashes[strings.ReplaceAll(strings.ToLower(vr.Hash), "-", "")]
+			if !ok {
+				return nil, fmt.Errorf("phiên bản %d: hàm băm không được hỗ trợ: %s", vr.Version, vr.Hash)
+			}
+			kv.Hash = hash
+		}
+		// Không biết đã dùng bao nhiêu trong phần đã dành trước nên coi như dùng hết
+		kv.encryptions, kv.encryptionsSaved = vr.Encryptions, vr.Encryptions
+		key.versions = append(key.versions, kv)
+	}
+	return key, nil
+}
+
+// Mã hóa và ghi khóa xuống keystore
+func (reg *KeyRegistry) saveKey(key *Key) error {
+	record := keyRecord{
+		ID:        key.ID,
+		Type:      key.Type,
+		CreatedAt: key.CreatedAt,
+		Disabled:  key.Disabled,
+	}
+	for _, kv := range key.allVersions() {
+		material, err := marshalKeyMaterial(key.Type, kv)
+		if err != nil {
+			return err
+		}
+		vr := keyVersionRecord{
+			Version:   kv.Version,
+			CreatedAt: kv.CreatedAt,
+			Material:  material,
+		}
+		if kv.Hash != 0 {
+			vr.Hash = kv.Hash.String()
+		}
+		key.mu.RLock()
+		vr.Encryptions = kv.encryptionsSaved
+		key.mu.RUnlock()
+		record.Versions = append(record.Versions, vr)
+	}
+
+	plaintext, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return reg.ks.save(key.ID, key.Type, plaintext)
+}
+
+// Sinh khóa mới với tên id và lưu vào registry
+func (reg *KeyRegistry) generate(keyType, id string, opts KeyOptions) (*Key, error) {
+	if !keyIDPattern.MatchString(id) {
+		return nil, fmt.Errorf("keyId không hợp lệ: %q", id)
+	}
+
+	kv, err := generateKey(keyType, opts)
+	if err != nil {
+		return nil, err
+	}
+	return reg.add(id, keyType, kv)
+}
+
+// Thêm khóa (vừa sinh hoặc vừa nhập) với tên id vào registry làm phiên bản 1
+func (reg *KeyRegistry) add(id, keyType string, kv *KeyVersion) (*Key, error) {
+	if !keyIDPattern.MatchString(id) {
+		return nil, fmt.Errorf("keyId không hợp lệ: %q", id)
+	}
+	now := time.Now().UTC()
+	kv.Version, kv.CreatedAt = 1, now
+	kv.setSignatureHash()
+	key := &Key{ID: id, Type: keyType, CreatedAt: now, versions: []*KeyVersion{kv}}
+
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	if _, exists := reg.keys[id]; exists {
+		return nil, fmt.Errorf("keyId %q đã tồn tại", id)
+	}
+	if err := reg.saveKey(key); err != nil {
+		return nil, err
+	}
+	reg.keys[id] = key
+	return key, nil
+}
+
+// Xoay vòng khóa: sinh phiên bản mới (cùng kích thước/đường cong nếu không chỉ định),
+// các phiên bản cũ được giữ lại để giải mã và xác thực dữ liệu cũ
+func (reg *KeyRegistry) rotate(id string, opts KeyOptions) (*Key, error) {
+	key, ok := reg.get(id)
+	if !ok {
+		return nil, fmt.Errorf("không tìm thấy khóa %q", id)
+	}
+
+	current := key.latest()
+	if opts.Bits == 0 && opts.QBits == 0 && opts.Curve == "" && opts.Group == "" && opts.CurveParams == nil {
+		opts = keyVersionOptions(current)
+	} else if opts.Bits == 0 && opts.QBits != 0 {
+		// Chỉ đổi kích thước q thì giữ kích thước p hiện tại
+		opts.Bits = keyVersionOptions(current).Bits
+	}
+	kv, err := generateKey(key.Type, opts)
+	if err != nil {
+		return nil, err
+	}
+	kv.Version = current.Version + 1
+	kv.CreatedAt = time.Now().UTC()
+	kv.setSignatureHash()
+
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	key.mu.Lock()
+	if key.versions[len(key.versions)-1] != current {
+		key.mu.Unlock()
+		return nil, fmt.Errorf("khóa %q vừa được xoay vòng bởi yêu cầu khác", id)
+	}
+	key.versions = append(key.versions, kv)
+	key.mu.Unlock()
+
+	if err := reg.saveKey(key); err != nil {
+		key.mu.Lock()
+		key.versions = key.versions[:len(key.versions)-1]
+		key.mu.Unlock()
+		return nil, err
+	}
+	return key, nil
+}
+
+// Tham số sinh khóa tương ứng với một phiên bản khóa có sẵn
+func keyVersionOptions(kv *KeyVersion) KeyOptions {
+	switch {
+	case kv.RSA != nil:
+		return KeyOptions{Bits: kv.RSA.N.BitLen()}
+	case kv.ElGamal != nil:
+		return KeyOptions{Bits: kv.ElGamal.P.BitLen(), Group: kv.ElGamal.Group}
+	case kv.DSA != nil:
+		return KeyOptions{Bits: kv.DSA.P.BitLen(), QBits: kv.DSA.Q.BitLen()}
+	case kv.Secret != nil:
+		return KeyOptions{Bits: len(kv.Secret) * 8}
+	case kv.ECDSA != nil:
+		return KeyOptions{Curve: kv.ECDSA.Curve.Params().Name}
+	case kv.ECIES != nil:
+		return KeyOptions{Curve: eciesCurveName(kv.ECIES.Curve())}
+	case kv.EC != nil:
+		if ecCurves[kv.EC.Curve.Name] == kv.EC.Curve {
+			return KeyOptions{Curve: kv.EC.Curve.Name}
+		}
+		params := kv.EC.Curve.params()
+		return KeyOptions{CurveParams: &params}
+	}
+	return KeyOptions{}
+}
+
+// Đặt khóa mặc định cho một loại khóa
+func (reg *KeyRegistry) setDefault(keyType, id string) error {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	key, ok := reg.keys[id]
+	if !ok {
+		return fmt.Errorf("không tìm thấy khóa %q", id)
+	}
+	if key.Type != keyType {
+		return fmt.Errorf("khóa %q không phải loại %s", id, keyType)
+	}
+	reg.defaults[keyType] = id
+	return nil
+}
+
+// Tìm khóa theo keyId, dùng khóa mặc định của loại khóa khi keyId rỗng
+func (reg *KeyRegistry) resolve(keyType, id string) (*Key, error) {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+
+	if id == "" {
+		id = reg.defaults[keyType]
+	}
+	key, ok := reg.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("không tìm thấy khóa %q", id)
+	}
+	if key.Type != keyType {
+		return nil, fmt.Errorf("khóa %q là khóa %s, không dùng được cho %s", id, key.Type, keyType)
+	}
+	if key.Disabled {
+		return nil, fmt.Errorf("khóa %q đã bị vô hiệu hóa", id)
+	}
+	return key, nil
+}
+
+// Tìm khóa theo keyId, kể cả khóa đã bị vô hiệu hóa
+func (reg *KeyRegistry) get(id string) (*Key, bool) {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+
+	key, ok := reg.keys[id]
+	return key, ok
+}
+
+// Kiểm tra khóa có phải khóa mặc định của loại khóa đó không
+func (reg *KeyRegistry) isDefault(key *Key) bool {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+
+	return reg.defaults[key.Type] == key.ID
+}
+
+// Kiểm tra khóa có đang bị vô hiệu hóa không
+func (reg *KeyRegistry) isDisabled(key *Key) bool {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+
+	return key.Disabled
+}
+
+// Bật hoặc vô hiệu hóa một khóa
+func (reg *KeyRegistry) setDisabled(id string, disabled bool) (*Key, error) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	key, ok := reg.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("không tìm thấy khóa %q", id)
+	}
+	if key.Disabled == disabled {
+		return key, nil
+	}
+
+	key.Disabled = disabled
+	if err := reg.saveKey(key); err != nil {
+		key.Disabled = !disabled
+		return nil, err
+	}
+	return key, nil
+}
+
+// Xóa khóa khỏi registry và keystore, không cho xóa khóa mặc định
+func (reg *KeyRegistry) delete(id string) error {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	key, ok := reg.keys[id]
+	if !ok {
+		return fmt.Errorf("không tìm thấy khóa %q", id)
+	}
+	if reg.defaults[key.Type] == id {
+		return fmt.Errorf("không thể xóa khóa mặc định %q", id)
+	}
+	if err := reg.ks.delete(id); err != nil {
+		return err
+	}
+	delete(reg.keys, id)
+	return nil
+}
+
+// Danh sách tất cả khóa, sắp xếp theo thời gian tạo
+func (reg *KeyRegistry) list() []*Key {
+	return reg.listByType("")
+}
+
+// Danh sách khóa của một loại (tất cả khóa nếu keyType rỗng), sắp xếp theo thời gian tạo
+func (reg *KeyRegistry) listByType(keyType string) []*Key {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+
+	var keys []*Key
+	for _, key := range reg.keys {
+		if keyType == "" || key.Type == keyType {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].CreatedAt.Equal(keys[j].CreatedAt) {
+			return keys[i].ID < keys[j].ID
+		}
+		return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+	})
+	return keys
+}
+
+// Sinh dữ liệu khóa theo loại khóa
+func generateKey(keyType string, opts KeyOptions) (*KeyVersion, error) {
+	key := &KeyVersion{}
+	var err error
+
+	switch keyType {
+	case KeyTypeRSA:
+		if opts.Bits == 0 {
+			opts.Bits = 2048
+		}
+		if opts.Bits < 1024 {
+			return nil, errors.New("khóa RSA phải có ít nhất 1024 bit")
+		}
+		key.RSA, err = generateRSAKeys(opts.Bits)
+	case KeyTypeElGamal:
+		if opts.Group != "" {
+			key.ElGamal, err = generateElGamalGroupKeys(opts.Group)
+			break
+		}
+		if opts.Bits == 0 {
+			opts.Bits = 512
+		}
+		key.ElGamal, err = generateElGamalKeys(opts.Bits)
+	case KeyTypeECC:
+		key.ECC, err = generateECCKeys()
+	case KeyTypeECDSA:
+		if opts.Curve == "" {
+			opts.Curve = "P-521"
+		}
+		key.ECDSA, err = generateECCKey(opts.Curve)
+	case KeyTypeECIES:
+		if opts.Curve == "" {
+			opts.Curve = "X25519"
+		}
+		key.ECIES, err = generateECIESKey(opts.Curve)
+	case KeyTypeEC:
+		var curve *Curve
+		if curve, err = resolveCurve(opts.Curve, opts.CurveParams); err != nil {
+			return nil, err
+		}
+		key.EC, err = generateECKey(curve)
+	case KeyTypeEd25519:
+		_, key.Ed25519, err = ed25519.GenerateKey(rand.Reader)
+	case KeyTypeSecp256k1:
+		key.Secp256k1, err = generateSecp256k1Key()
+	case KeyTypeSchnorr:
+		key.Schnorr, err = generateSecp256k1Key()
+	case KeyTypeDSA:
+		key.DSA, err = generateDSAKey(opts.Bits, opts.QBits)
+	case KeyTypeAES:
+		if opts.Bits == 0 {
+			opts.Bits = 256
+		}
+		if !aesKeySizes[opts.Bits] {
+			return nil, errors.New("khóa AES phải có 128, 192 hoặc 256 bit")
+		}
+		key.Secret, err = generateSecretKey(opts.Bits)
+	case KeyTypeChaCha20:
+		if opts.Bits != 0 && opts.Bits != 256 {
+			return nil, errors.New("khóa ChaCha20 phải có 256 bit")
+		}
+		key.Secret, err = generateSecretKey(256)
+	default:
+		return nil, fmt.Errorf("loại khóa không được hỗ trợ: %s", keyType)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// Dữ liệu khóa ElGamal khi lưu xuống đĩa
+type elGamalKeyData struct {
+	Group string `json:"group,omitempty"`
+	P     string `json:"p"`
+	G     string `json:"g"`
+	Q     string `json:"q,omitempty"`
+	X     string `json:"x"`
+	Y     string `json:"y"`
+}
+
+// Dữ liệu khóa DSA khi lưu xuống đĩa, kèm seed và counter để kiểm tra lại tham số miền
+type dsaKeyData struct {
+	P       string `json:"p"`
+	Q       string `json:"q"`
+	G       string `json:"g"`
+	X       string `json:"x"`
+	Y       string `json:"y"`
+	Seed    string `json:"seed,omitempty"`
+	Counter int    `json:"counter,omitempty"`
+}
+
+// Dữ liệu khóa ECC (đường cong tự định nghĩa) khi lưu xuống đĩa
+type eccKeyData struct {
+	D string `json:"d"`
+	X string `json:"x"`
+	Y string `json:"y"`
+}
+
+// Dữ liệu khóa EC-ElGamal khi lưu xuống đĩa, kèm đầy đủ tham số đường cong
+type ecKeyData struct {
+	Curve CurveParams `json:"curve"`
+	D     string      `json:"d"`
+	X     string      `json:"x"`
+	Y     string      `json:"y"`
+}
+
+// Mã hóa dữ liệu khóa: PKCS#8 cho RSA/ECDSA/ECIES/Ed25519, JSON cho ElGamal/DSA/ECC/EC, 32 byte d cho secp256k1/Schnorr,
+// các byte khóa bí mật cho AES/ChaCha20
+func marshalKeyMaterial(keyType string, key *KeyVersion) ([]byte, error) {
+	switch keyType {
+	case KeyTypeRSA:
+		return x509.MarshalPKCS8PrivateKey(key.RSA)
+	case KeyTypeElGamal:
+		data := elGamalKeyData{
+			Group: key.ElGamal.Group,
+			P:     key.ElGamal.P.Text(16),
+			G:     key.ElGamal.G.Text(16),
+			X:     key.ElGamal.X.Text(16),
+			Y:     key.ElGamal.Y.Text(16),
+		}
+		if key.ElGamal.Q != nil {
+			data.Q = key.ElGamal.Q.Text(16)
+		}
+		return json.Marshal(data)
+	case KeyTypeDSA:
+		return json.Marshal(dsaKeyData{
+			P:       key.DSA.P.Text(16),
+			Q:       key.DSA.Q.Text(16),
+			G:       key.DSA.G.Text(16),
+			X:       key.DSA.X.Text(16),
+			Y:       key.DSA.Y.Text(16),
+			Seed:    hex.EncodeToString(key.DSA.Seed),
+			Counter: key.DSA.Counter,
+		})
+	case KeyTypeECC:
+		return json.Marshal(eccKeyData{
+			D: key.ECC.D.Text(16),
+			X: key.ECC.X.Text(16),
+			Y: key.ECC.Y.Text(16),
+		})
+	case KeyTypeECDSA:
+		return x509.MarshalPKCS8PrivateKey(key.ECDSA)
+	case KeyTypeECIES:
+		return x509.MarshalPKCS8PrivateKey(key.ECIES)
+	case KeyTypeEd25519:
+		return x509.MarshalPKCS8PrivateKey(key.Ed25519)
+	case KeyTypeSecp256k1:
+		return ethcrypto.FromECDSA(key.Secp256k1), nil
+	case KeyTypeSchnorr:
+		return ethcrypto.FromECDSA(key.Schnorr), nil
+	case KeyTypeAES, KeyTypeChaCha20:
+		return key.Secret, nil
+	case KeyTypeEC:
+		return json.Marshal(ecKeyData{
+			Curve: key.EC.Curve.params(),
+			D:     key.EC.D.Text(16),
+			X:     key.EC.X.Text(16),
+			Y:     key.EC.Y.Text(16),
+		})
+	}
+	return nil, fmt.Errorf("loại khóa không được hỗ trợ: %s", keyType)
+}
+
+// Giải mã dữ liệu khóa đã lưu
+func parseKeyMaterial(keyType string, material []byte) (*KeyVersion, error) {
+	key := &KeyVersion{}
+
+	switch keyType {
+	case KeyTypeRSA, KeyTypeECDSA:
+		parsed, err := x509.ParsePKCS8PrivateKey(material)
+		if err != nil {
+			return nil, err
+		}
+		var ok bool
+		if keyType == KeyTypeRSA {
+			key.RSA, ok = parsed.(*rsa.PrivateKey)
+		} else {
+			key.ECDSA, ok = parsed.(*ecdsa.PrivateKey)
+		}
+		if !ok {
+			return nil, fmt.Errorf("dữ liệu khóa không phải %s", keyType)
+		}
+
+	case KeyTypeECIES:
+		parsed, err := x509.ParsePKCS8PrivateKey(material)
+		if err != nil {
+			return nil, err
+		}
+		if key.ECIES, err = toECDHPrivateKey(parsed); err != nil {
+			return nil, err
+		}
+
+	case KeyTypeEd25519:
+		parsed, err := x509.ParsePKCS8PrivateKey(material)
+		if err != nil {
+			return nil, err
+		}
+		var ok bool
+		if key.Ed25519, ok = parsed.(ed25519.PrivateKey); !ok {
+			return nil, fmt.Errorf("dữ liệu khóa không phải %s", keyType)
+		}
+
+	case KeyTypeSecp256k1:
+		var err error
+		if key.Secp256k1, err = ethcrypto.ToECDSA(material); err != nil {
+			return nil, err
+		}
+
+	case KeyTypeSchnorr:
+		var err error
+		if key.Schnorr, err = ethcrypto.ToECDSA(material); err != nil {
+			return nil, err
+		}
+
+	case KeyTypeAES, KeyTypeChaCha20:
+		if keyType == KeyTypeAES && !aesKeySizes[len(material)*8] || keyType == KeyTypeChaCha20 && len(material) != 32 {
+			return nil, fmt.Errorf("độ dài khóa %s không hợp lệ", keyType)
+		}
+		key.Secret = material
+
+	case KeyTypeElGamal:
+		var data elGamalKeyData
+		if err := json.Unmarshal(material, &data); err != nil {
+			return nil, err
+		}
+		values, err := parseHexInts(data.P, data.G, data.X, data.Y)
+		if err != nil {
+			return nil, err
+		}
+		key.ElGamal = &ElGamalKey{Group: data.Group, P: values[0], G: values[1], X: values[2], Y: values[3]}
+		if data.Q != "" {
+			q, err := parseHexInts(data.Q)
+			if err != nil {
+				return nil, err
+			}
+			key.ElGamal.Q = q[0]
+		}
+
+	case KeyTypeDSA:
+		var data dsaKeyData
+		if err := json.Unmarshal(material, &data); err != nil {
+			return nil, err
+		}
+		values, err := parseHexInts(data.P, data.Q, data.G, data.X, data.Y)
+		if err != nil {
+			return nil, err
+		}
+		seed, err := hex.DecodeString(data.Seed)
+		if err != nil {
+			return nil, err
+		}
+		key.DSA = &DSAKey{P: values[0], Q: values[1], G: values[2], X: values[3], Y: values[4], Seed: seed, Counter: data.Counter}
+		if err := validateDSAKey(key.DSA); err != nil {
+			return nil, err
+		}
+
+	case KeyTypeECC:
+		var data eccKeyData
+		if err := json.Unmarshal(material, &data); err != nil {
+			return nil, err
+		}
+		values, err := parseHexInts(data.D, data.X, data.Y)
+		if err != nil {
+			return nil, err
+		}
+		key.ECC = &ECCKey{D: values[0], X: values[1], Y: values[2]}
+
+	case KeyTypeEC:
+		var data ecKeyData
+		if err := json.Unmarshal(material, &data); err != nil {
+			return nil, err
+		}
+		curve, err := data.Curve.curve()
+		if err != nil {
+			return nil, err
+		}
+		// Chỉ dùng đường cong có sẵn khi tham số lưu trùng khớp;
+		// tham số khác mà mang tên đường cong có sẵn thì đổi tên thành "custom"
+		if builtin, ok := ecCurves[curve.Name]; ok && builtin.equal(curve) {
+			curve = builtin
+		} else {
+			if ok {
+				curve.Name = "custom"
+			}
+			if err := validateCurve(curve); err != nil {
+				return nil, err
+			}
+		}
+		values, err := parseHexInts(data.D, data.X, data.Y)
+		if err != nil {
+			return nil, err
+		}
+		key.EC = &ECKey{Curve: curve, D: values[0], X: values[1], Y: values[2]}
+		if err := validateECKey(key.EC); err != nil {
+			return nil, err
+		}
+
+	default:
+		return nil, fmt.Errorf("loại khóa không được hỗ trợ: %s", keyType)
+	}
+	return key, nil
+}
@@ -0,0 +1,434 @@
+// keys_http.go
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+)
+
+// Yêu cầu sinh khóa mới qua POST /keys
+type CreateKeyRequest struct {
+	Algorithm string `json:"algorithm"`
+	KeyID     string `json:"keyId,omitempty"`
+	Bits      int    `json:"bits,omitempty"`
+	Curve     string `json:"curve,omitempty"`
+	Group     string `json:"group,omitempty"`
+
+	// Độ dài bit của q (N) cho khóa DSA, bits là độ dài của p (L)
+	QBits int `json:"qBits,omitempty"`
+
+	// Tham số đường cong tự chọn cho khóa EC (EC-ElGamal)
+	CurveParams *CurveParams `json:"curveParams,omitempty"`
+}
+
+// Yêu cầu nhập khóa PEM qua POST /keys/import
+type ImportKeyRequest struct {
+	KeyID string `json:"keyId,omitempty"`
+	PEM   string `json:"pem"`
+}
+
+// Khóa xuất ra dưới dạng PEM
+type ExportKeyResponse struct {
+	KeyID   string `json:"keyId"`
+	Version int    `json:"version"`
+	Format  string `json:"format"`
+	PEM     string `json:"pem"`
+}
+
+// Yêu cầu xoay vòng khóa qua POST /keys/{id}/rotate
+type RotateKeyRequest struct {
+	Bits        int          `json:"bits,omitempty"`
+	Curve       string       `json:"curve,omitempty"`
+	Group       string       `json:"group,omitempty"`
+	QBits       int          `json:"qBits,omitempty"`
+	CurveParams *CurveParams `json:"curveParams,omitempty"`
+}
+
+// Thông tin một phiên bản khóa
+type KeyVersionInfo struct {
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"createdAt"`
+	Kid       string    `json:"kid,omitempty"`
+}
+
+// Thông tin (không bí mật) của một khóa, phần công khai là của phiên bản mới nhất
+type KeyInfo struct {
+	KeyID     string            `json:"keyId"`
+	Algorithm string            `json:"algorithm"`
+	CreatedAt time.Time         `json:"createdAt"`
+	Disabled  bool              `json:"disabled"`
+	Default   bool              `json:"default"`
+	Version   int               `json:"version"`
+	Versions  []KeyVersionInfo  `json:"versions,omitempty"`
+	Bits      int               `json:"bits,omitempty"`
+	Curve     string            `json:"curve,omitempty"`
+	Group     string            `json:"group,omitempty"`
+	Kid       string            `json:"kid,omitempty"`
+	PublicKey map[string]string `json:"publicKey,omitempty"`
+}
+
+// Tạo thông tin khóa, kèm phần công khai và các phiên bản nếu withPublic
+func newKeyInfo(key *Key, withPublic bool) KeyInfo {
+	kv := key.latest()
+	info := KeyInfo{
+		KeyID:     key.ID,
+		Algorithm: key.Type,
+		CreatedAt: key.CreatedAt,
+		Disabled:  registry.isDisabled(key),
+		Default:   registry.isDefault(key),
+		Version:   kv.Version,
+		Kid:       keyKid(key.Type, kv),
+	}
+	if withPublic {
+		for _, v := range key.allVersions() {
+			info.Versions = append(info.Versions, KeyVersionInfo{
+				Version:   v.Version,
+				CreatedAt: v.CreatedAt,
+				Kid:       keyKid(key.Type, v),
+			})
+		}
+	}
+
+	switch key.Type {
+	case KeyTypeRSA:
+		info.Bits = kv.RSA.N.BitLen()
+		if withPublic {
+			info.PublicKey = map[string]string{
+				"n": kv.RSA.N.Text(16),
+				"e": strconv.FormatInt(int64(kv.RSA.E), 16),
+			}
+		}
+	case KeyTypeElGamal:
+		info.Bits = kv.ElGamal.P.BitLen()
+		info.Group = kv.ElGamal.Group
+		if withPublic {
+			info.PublicKey = map[string]string{
+				"p": kv.ElGamal.P.Text(16),
+				"g": kv.ElGamal.G.Text(16),
+				"y": kv.ElGamal.Y.Text(16),
+			}
+			if kv.ElGamal.Q != nil {
+				info.PublicKey["q"] = kv.ElGamal.Q.Text(16)
+			}
+		}
+	case KeyTypeDSA:
+		info.Bits = kv.DSA.P.BitLen()
+		if withPublic {
+			info.PublicKey = map[string]string{
+				"p": kv.DSA.P.Text(16),
+				"q": kv.DSA.Q.Text(16),
+				"g": kv.DSA.G.Text(16),
+				"y": kv.DSA.Y.Text(16),
+			}
+			if len(kv.DSA.Seed) > 0 {
+				info.PublicKey["seed"] = hex.EncodeToString(kv.DSA.Seed)
+				info.PublicKey["counter"] = strconv.Itoa(kv.DSA.Counter)
+			}
+		}
+	case KeyTypeAES, KeyTypeChaCha20:
+		// Khóa đối xứng không có phần công khai
+		info.Bits = len(kv.Secret) * 8
+	case KeyTypeECC:
+		info.Bits = curveP.BitLen()
+		if withPublic {
+			info.PublicKey = map[string]string{
+				"x": kv.ECC.X.Text(16),
+				"y": kv.ECC.Y.Text(16),
+			}
+		}
+	case KeyTypeECDSA:
+		info.Curve = kv.ECDSA.Curve.Params().Name
+		info.Bits = kv.ECDSA.Curve.Params().BitSize
+		if withPublic {
+			x, y, err := ecdsaPublicPoint(&kv.ECDSA.PublicKey)
+			if err == nil {
+				info.PublicKey = map[string]string{
+					"x": hex.EncodeToString(x),
+					"y": hex.EncodeToString(y),
+				}
+			}
+		}
+	case KeyTypeECIES:
+		info.Curve = eciesCurveName(kv.ECIES.Curve())
+		if withPublic {
+			info.PublicKey = map[string]string{
+				"point": hex.EncodeToString(kv.ECIES.PublicKey().Bytes()),
+			}
+		}
+	case KeyTypeEd25519:
+		info.Curve = "Ed25519"
+		info.Bits = 256
+		if withPublic {
+			info.PublicKey = map[string]string{
+				"x": hex.EncodeToString(kv.Ed25519.Public().(ed25519.PublicKey)),
+			}
+		}
+	case KeyTypeSecp256k1:
+		info.Curve = "secp256k1"
+		info.Bits = 256
+		if withPublic {
+			point := ethcrypto.FromECDSAPub(&kv.Secp256k1.PublicKey)
+			info.PublicKey = map[string]string{
+				"x":       hex.EncodeToString(point[1:33]),
+				"y":       hex.EncodeToString(point[33:]),
+				"address": ethereumAddress(&kv.Secp256k1.PublicKey),
+			}
+		}
+	case KeyTypeSchnorr:
+		info.Curve = "secp256k1"
+		info.Bits = 256
+		if withPublic {
+			info.PublicKey = map[string]string{
+				"x": hex.EncodeToString(schnorrPublicKey(kv.Schnorr)),
+			}
+		}
+	case KeyTypeEC:
+		info.Curve = kv.EC.Curve.Name
+		info.Bits = kv.EC.Curve.P.BitLen()
+		if withPublic {
+			params := kv.EC.Curve.params()
+			info.PublicKey = map[string]string{
+				"p":  params.P,
+				"a":  params.A,
+				"b":  params.B,
+				"gx": params.Gx,
+				"gy": params.Gy,
+				"n":  params.N,
+				"h":  params.H,
+				"x":  kv.EC.X.Text(16),
+				"y":  kv.EC.Y.Text(16),
+			}
+		}
+	}
+	return info
+}
+
+// Phiên bản khóa theo tham số ?version=N, mặc định là phiên bản mới nhất
+func requestedKeyVersion(key *Key, r *http.Request) (*KeyVersion, error) {
+	v := r.URL.Query().Get("version")
+	if v == "" {
+		return key.latest(), nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return nil, fmt.Errorf("version không hợp lệ: %q", v)
+	}
+	return key.version(n)
+}
+
+// Sinh keyId ngẫu nhiên dạng <loại khóa>-<8 ký tự hex>
+func randomKeyID(keyType string) (string, error) {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return strings.ToLower(keyType) + "-" + hex.EncodeToString(b), nil
+}
+
+// GET /keys: danh sách khóa; POST /keys: sinh khóa mới
+func keysHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		infos := []KeyInfo{}
+		for _, key := range registry.list() {
+			infos = append(infos, newKeyInfo(key, false))
+		}
+		json.NewEncoder(w).Encode(infos)
+
+	case http.MethodPost:
+		var req CreateKeyRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request", http.StatusBadRequest)
+			return
+		}
+
+		keyType := strings.ToUpper(req.Algorithm)
+		if req.KeyID == "" {
+			id, err := randomKeyID(keyType)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			req.KeyID = id
+		}
+
+		key, err := registry.generate(keyType, req.KeyID, KeyOptions{Bits: req.Bits, QBits: req.QBits, Curve: req.Curve, Group: req.Group, CurveParams: req.CurveParams})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(newKeyInfo(key, true))
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// GET /keys/{id}: thông tin và phần công khai của khóa; DELETE /keys/{id}: xóa khóa
+func keyHandler(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	switch r.Method {
+	case http.MethodGet:
+		key, ok := registry.get(id)
+		if !ok {
+			http.Error(w, "Key not found", http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(newKeyInfo(key, true))
+
+	case http.MethodDelete:
+		if _, ok := registry.get(id); !ok {
+			http.Error(w, "Key not found", http.StatusNotFound)
+			return
+		}
+		if err := registry.delete(id); err != nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// POST /keys/{id}/disable và POST /keys/{id}/enable
+func keyStatusHandler(disabled bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		id := r.PathValue("id")
+		if _, ok := registry.get(id); !ok {
+			http.Error(w, "Key not found", http.StatusNotFound)
+			return
+		}
+		key, err := registry.setDisabled(id, disabled)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(newKeyInfo(key, false))
+	}
+}
+
+// POST /keys/import: nhập khóa bí mật RSA/ECDSA từ PEM
+func importKeyHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req ImportKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	keyType, kv, err := importKeyPEM(req.PEM)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.KeyID == "" {
+		if req.KeyID, err = randomKeyID(keyType); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	key, err := registry.add(req.KeyID, keyType, kv)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(newKeyInfo(key, true))
+}
+
+// Xuất khóa bí mật qua API chỉ được bật khi đặt KEYSTORE_ALLOW_PRIVATE_EXPORT=true
+func privateKeyExportAllowed() bool {
+	allowed, _ := strconv.ParseBool(os.Getenv("KEYSTORE_ALLOW_PRIVATE_EXPORT"))
+	return allowed
+}
+
+// GET /keys/{id}/export?format=pkix|pkcs1-public|pkcs1|sec1|pkcs8&version=N: xuất khóa dạng PEM,
+// các định dạng khóa bí mật (pkcs1, sec1, pkcs8) chỉ dùng được khi privateKeyExportAllowed
+func exportKeyHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	key, ok := registry.get(r.PathValue("id"))
+	if !ok {
+		http.Error(w, "Key not found", http.StatusNotFound)
+		return
+	}
+	if registry.isDisabled(key) {
+		http.Error(w, "Key is disabled", http.StatusConflict)
+		return
+	}
+
+	kv, err := requestedKeyVersion(key, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "pkix"
+	}
+	if privateKeyFormats[strings.ToLower(format)] && !privateKeyExportAllowed() {
+		http.Error(w, "Private key export is disabled (set KEYSTORE_ALLOW_PRIVATE_EXPORT=true)", http.StatusForbidden)
+		return
+	}
+	data, err := exportKeyPEM(kv, format)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	json.NewEncoder(w).Encode(ExportKeyResponse{KeyID: key.ID, Version: kv.Version, Format: format, PEM: data})
+}
+
+// POST /keys/{id}/rotate: sinh phiên bản khóa mới, giữ các phiên bản cũ để giải mã/xác thực
+func rotateKeyHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req RotateKeyRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request", http.StatusBadRequest)
+			return
+		}
+	}
+
+	id := r.PathValue("id")
+	if _, ok := registry.get(id); !ok {
+		http.Error(w, "Key not found", http.StatusNotFound)
+		return
+	}
+	key, err := registry.rotate(id, KeyOptions{Bits: req.Bits, QBits: req.QBits, Curve: req.Curve, Group: req.Group, CurveParams: req.CurveParams})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	json.NewEncoder(w).Encode(newKeyInfo(key, true))
+}
@@ -0,0 +1,383 @@
+package main
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const testKeystorePassphrase = "mật khẩu thử"
+
+func testKeystore(t *testing.T, dir, passphrase string) *Keystore {
+	t.Helper()
+	ks, err := openKeystore(dir, passphrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ks
+}
+
+// Registry rỗng trên một keystore tạm, không sinh sẵn khóa mặc định cho mọi loại khóa
+func testKeyRegistry(t *testing.T, dir string) *KeyRegistry {
+	t.Helper()
+	return &KeyRegistry{
+		ks:       testKeystore(t, dir, testKeystorePassphrase),
+		keys:     map[string]*Key{},
+		defaults: map[string]string{},
+	}
+}
+
+// Dùng registry thử làm registry toàn cục của các handler trong suốt một test
+func useTestRegistry(t *testing.T) *KeyRegistry {
+	t.Helper()
+	saved := registry
+	registry = testKeyRegistry(t, t.TempDir())
+	t.Cleanup(func() { registry = saved })
+	return registry
+}
+
+// Gọi handler đăng ký theo pattern, body được mã hóa JSON (bỏ qua nếu nil), out nhận phản hồi JSON
+func serveJSON(t *testing.T, pattern string, handler http.HandlerFunc, method, path string, body, out any) int {
+	t.Helper()
+	var data []byte
+	if body != nil {
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(pattern, handler)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(method, path, bytes.NewReader(data)))
+	if out != nil && rec.Code < 300 {
+		if err := json.Unmarshal(rec.Body.Bytes(), out); err != nil {
+			t.Fatalf("%s %s: %v (%s)", method, path, err, rec.Body.String())
+		}
+	}
+	return rec.Code
+}
+
+func TestKeystoreReload(t *testing.T) {
+	dir := t.TempDir()
+	reg := testKeyRegistry(t, dir)
+	aesKey, err := reg.generate(KeyTypeAES, "aes-1", KeyOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecdsaKey, err := reg.generate(KeyTypeECDSA, "ecdsa-1", KeyOptions{Curve: "P-384"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Mở lại keystore bằng đúng passphrase cho lại đúng dữ liệu khóa
+	reloaded := testKeyRegistry(t, dir)
+	got, err := reloaded.loadKey("aes-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Type != KeyTypeAES || !bytes.Equal(got.latest().Secret, aesKey.latest().Secret) {
+		t.Fatal("khóa AES khác sau khi đọc lại")
+	}
+	got, err = reloaded.loadKey("ecdsa-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Type != KeyTypeECDSA || !got.latest().ECDSA.Equal(ecdsaKey.latest().ECDSA) {
+		t.Fatal("khóa ECDSA khác sau khi đọc lại")
+	}
+}
+
+func TestKeystoreRejectsWrongPassphrase(t *testing.T) {
+	dir := t.TempDir()
+	ks := testKeystore(t, dir, testKeystorePassphrase)
+	if err := ks.save("k", KeyTypeAES, []byte("dữ liệu khóa")); err != nil {
+		t.Fatal(err)
+	}
+
+	wrong := testKeystore(t, dir, "sai mật khẩu")
+	if _, _, err := wrong.load("k"); err == nil {
+		t.Fatal("giải mã được keystore bằng passphrase sai")
+	}
+	if _, err := openKeystore(dir, ""); err == nil {
+		t.Fatal("mở được keystore với passphrase rỗng")
+	}
+}
+
+func TestKeystoreRejectsTamperedAssociatedData(t *testing.T) {
+	dir := t.TempDir()
+	ks := testKeystore(t, dir, testKeystorePassphrase)
+	if err := ks.save("k", KeyTypeAES, []byte("dữ liệu khóa")); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "k.key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Đổi tên file: tên khóa nằm trong dữ liệu liên kết nên không giải mã được
+	if err := os.WriteFile(filepath.Join(dir, "other.key"), data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := ks.load("other"); err == nil {
+		t.Fatal("giải mã được file khóa đã đổi tên")
+	}
+
+	// Đổi loại khóa ghi trong file
+	var entry keystoreEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		t.Fatal(err)
+	}
+	entry.Type = KeyTypeChaCha20
+	tampered, err := json.Marshal(entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "k.key"), tampered, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := ks.load("k"); err == nil {
+		t.Fatal("giải mã được file khóa đã đổi loại khóa")
+	}
+}
+
+func TestKeyRotationKeepsOldVersions(t *testing.T) {
+	reg := useTestRegistry(t)
+	if _, err := reg.generate(KeyTypeAES, "aes", KeyOptions{Bits: 128}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reg.generate(KeyTypeECDSA, "ecdsa", KeyOptions{Curve: "P-256"}); err != nil {
+		t.Fatal(err)
+	}
+
+	encrypt := EncryptRequest{Algorithm: "AES-GCM", KeyID: "aes", Message: "bản tin cũ"}
+	var old EncryptResponse
+	if code := serveJSON(t, "/encrypt", encryptHandler, http.MethodPost, "/encrypt", encrypt, &old); code != http.StatusOK {
+		t.Fatalf("mã hóa: %d", code)
+	}
+	sign := SignRequest{Algorithm: "ECDSA", KeyID: "ecdsa", Message: "bản tin cũ"}
+	var oldSig SignResponse
+	if code := serveJSON(t, "/sign", signHandler, http.MethodPost, "/sign", sign, &oldSig); code != http.StatusOK {
+		t.Fatalf("ký: %d", code)
+	}
+	if !strings.HasPrefix(old.EncryptedMessage, "v1:") || !strings.HasPrefix(oldSig.Signature, "v1:") {
+		t.Fatalf("thiếu tiền tố phiên bản: %q, %q", old.EncryptedMessage, oldSig.Signature)
+	}
+
+	// Xoay vòng giữ nguyên kích thước/đường cong của phiên bản hiện tại
+	for _, id := range []string{"aes", "ecdsa"} {
+		var info KeyInfo
+		if code := serveJSON(t, "/keys/{id}/rotate", rotateKeyHandler, http.MethodPost, "/keys/"+id+"/rotate", nil, &info); code != http.StatusOK {
+			t.Fatalf("xoay vòng %s: %d", id, code)
+		}
+		if info.Version != 2 || len(info.Versions) != 2 {
+			t.Fatalf("%s sau xoay vòng: phiên bản %d, %d phiên bản", id, info.Version, len(info.Versions))
+		}
+	}
+	aes, _ := reg.get("aes")
+	if len(aes.latest().Secret) != 16 || bytes.Equal(aes.latest().Secret, aes.allVersions()[0].Secret) {
+		t.Fatal("phiên bản AES mới không đúng kích thước hoặc trùng khóa cũ")
+	}
+
+	// Dữ liệu mới dùng phiên bản 2
+	var fresh EncryptResponse
+	serveJSON(t, "/encrypt", encryptHandler, http.MethodPost, "/encrypt", encrypt, &fresh)
+	if fresh.KeyVersion != 2 || !strings.HasPrefix(fresh.EncryptedMessage, "v2:") {
+		t.Fatalf("mã hóa sau xoay vòng dùng phiên bản %d: %q", fresh.KeyVersion, fresh.EncryptedMessage)
+	}
+
+	// Bản mã và chữ ký cũ vẫn dùng được với phiên bản 1
+	for _, ciphertext := range []string{old.EncryptedMessage, fresh.EncryptedMessage} {
+		var dec DecryptResponse
+		req := DecryptRequest{Algorithm: "AES-GCM", KeyID: "aes", EncryptedMessage: ciphertext}
+		if code := serveJSON(t, "/decrypt", decryptHandler, http.MethodPost, "/decrypt", req, &dec); code != http.StatusOK {
+			t.Fatalf("giải mã %q: %d", ciphertext, code)
+		}
+		if dec.DecryptedMessage != "bản tin cũ" {
+			t.Fatalf("giải mã %q: %q", ciphertext, dec.DecryptedMessage)
+		}
+	}
+	var verify VerifyResponse
+	req := VerifyRequest{Algorithm: "ECDSA", KeyID: "ecdsa", Message: "bản tin cũ", Signature: oldSig.Signature}
+	serveJSON(t, "/verify", verifyHandler, http.MethodPost, "/verify", req, &verify)
+	if !verify.IsValid || verify.KeyVersion != 1 {
+		t.Fatalf("chữ ký phiên bản 1 sau xoay vòng: %v, phiên bản %d", verify.IsValid, verify.KeyVersion)
+	}
+
+	// Bản mã gắn với phiên bản khác thì không giải mã được
+	var dec DecryptResponse
+	swapped := DecryptRequest{Algorithm: "AES-GCM", KeyID: "aes", EncryptedMessage: "v2:" + strings.TrimPrefix(old.EncryptedMessage, "v1:")}
+	if code := serveJSON(t, "/decrypt", decryptHandler, http.MethodPost, "/decrypt", swapped, &dec); code == http.StatusOK {
+		t.Fatal("giải mã được bản mã v1 bằng phiên bản 2")
+	}
+	missing := DecryptRequest{Algorithm: "AES-GCM", KeyID: "aes", EncryptedMessage: "v9:" + strings.TrimPrefix(old.EncryptedMessage, "v1:")}
+	if code := serveJSON(t, "/decrypt", decryptHandler, http.MethodPost, "/decrypt", missing, &dec); code == http.StatusOK {
+		t.Fatal("giải mã được bản mã của phiên bản không tồn tại")
+	}
+
+	// Các phiên bản được lưu xuống keystore
+	reloaded, err := reg.loadKey("aes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if versions := reloaded.allVersions(); len(versions) != 2 || !bytes.Equal(versions[0].Secret, aes.allVersions()[0].Secret) {
+		t.Fatalf("keystore có %d phiên bản sau xoay vòng", len(versions))
+	}
+}
+
+func TestKeyDisabledConcurrentAccess(t *testing.T) {
+	reg := useTestRegistry(t)
+	if _, err := reg.generate(KeyTypeEd25519, "ed", KeyOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Chạy với -race: đọc trạng thái khóa trong lúc bật/tắt khóa
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				switch i {
+				case 0:
+					serveJSON(t, "/keys/{id}/disable", keyStatusHandler(j%2 == 0), http.MethodPost, "/keys/ed/disable", nil, nil)
+				case 1:
+					serveJSON(t, "/keys/{id}", keyHandler, http.MethodGet, "/keys/ed", nil, nil)
+				case 2:
+					serveJSON(t, "/.well-known/jwks.json", jwksHandler, http.MethodGet, "/.well-known/jwks.json", nil, nil)
+				default:
+					serveJSON(t, "/keys", keysHandler, http.MethodGet, "/keys", nil, nil)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestExportKeyRestrictsPrivateFormats(t *testing.T) {
+	reg := useTestRegistry(t)
+	if _, err := reg.generate(KeyTypeECDSA, "ecdsa", KeyOptions{Curve: "P-256"}); err != nil {
+		t.Fatal(err)
+	}
+	export := func(format string) int {
+		return serveJSON(t, "/keys/{id}/export", exportKeyHandler, http.MethodGet, "/keys/ecdsa/export?format="+format, nil, &ExportKeyResponse{})
+	}
+
+	// Khóa công khai luôn xuất được, khóa bí mật cần bật KEYSTORE_ALLOW_PRIVATE_EXPORT
+	t.Setenv("KEYSTORE_ALLOW_PRIVATE_EXPORT", "")
+	if code := export("pkix"); code != http.StatusOK {
+		t.Fatalf("pkix: %d", code)
+	}
+	for _, format := range []string{"sec1", "pkcs8", "PKCS8"} {
+		if code := export(format); code != http.StatusForbidden {
+			t.Fatalf("%s khi chưa bật: %d", format, code)
+		}
+	}
+	t.Setenv("KEYSTORE_ALLOW_PRIVATE_EXPORT", "true")
+	if code := export("pkcs8"); code != http.StatusOK {
+		t.Fatalf("pkcs8 khi đã bật: %d", code)
+	}
+
+	// Khóa bị vô hiệu hóa không xuất được
+	if _, err := reg.setDisabled("ecdsa", true); err != nil {
+		t.Fatal(err)
+	}
+	for _, format := range []string{"pkix", "pkcs8"} {
+		if code := export(format); code != http.StatusConflict {
+			t.Fatalf("%s của khóa bị vô hiệu hóa: %d", format, code)
+		}
+	}
+}
+
+func TestDeleteKeyRefusesDefault(t *testing.T) {
+	reg := useTestRegistry(t)
+	for _, id := range []string{"aes-default", "aes-other"} {
+		if _, err := reg.generate(KeyTypeAES, id, KeyOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := reg.setDefault(KeyTypeAES, "aes-default"); err != nil {
+		t.Fatal(err)
+	}
+	del := func(id string) int {
+		return serveJSON(t, "/keys/{id}", keyHandler, http.MethodDelete, "/keys/"+id, nil, nil)
+	}
+
+	if code := del("aes-default"); code != http.StatusConflict {
+		t.Fatalf("xóa khóa mặc định: %d", code)
+	}
+	if _, ok := reg.get("aes-default"); !ok {
+		t.Fatal("khóa mặc định đã bị xóa khỏi registry")
+	}
+	if _, _, err := reg.ks.load("aes-default"); err != nil {
+		t.Fatalf("khóa mặc định đã bị xóa khỏi keystore: %v", err)
+	}
+
+	if code := del("aes-other"); code != http.StatusNoContent {
+		t.Fatalf("xóa khóa thường: %d", code)
+	}
+	if _, ok := reg.get("aes-other"); ok {
+		t.Fatal("khóa đã xóa vẫn còn trong registry")
+	}
+	if code := del("aes-other"); code != http.StatusNotFound {
+		t.Fatalf("xóa khóa không tồn tại: %d", code)
+	}
+}
+
+func TestECDSAKeyVersionRecordsSignatureHash(t *testing.T) {
+	dir := t.TempDir()
+	reg := testKeyRegistry(t, dir)
+	key, err := reg.generate(KeyTypeECDSA, "p384", KeyOptions{Curve: "P-384"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	kv := key.latest()
+	if kv.Hash != crypto.SHA384 {
+		t.Fatalf("phiên bản mới ghi hàm băm %v", kv.Hash)
+	}
+	reloaded, err := testKeyRegistry(t, dir).loadKey("p384")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reloaded.latest().Hash != crypto.SHA384 {
+		t.Fatalf("hàm băm sau khi đọc lại: %v", reloaded.latest().Hash)
+	}
+
+	// Chữ ký dạng cũ trên SHA-256 chỉ hợp lệ với phiên bản khóa không ghi hàm băm
+	pub := &kv.ECDSA.PublicKey
+	r, s, err := ecdsa.Sign(rand.Reader, kv.ECDSA, hashMessage(crypto.SHA256, "hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacy, _ := encodeECDSASignature(pub, r, s, ECDSAFormatLegacy)
+	if ok, _ := verifyECC(pub, kv.Hash, "hello", legacy); ok {
+		t.Fatal("phiên bản mới chấp nhận chữ ký trên SHA-256")
+	}
+	if ok, _ := verifyECC(pub, 0, "hello", legacy); !ok {
+		t.Fatal("phiên bản cũ từ chối chữ ký dạng cũ trên SHA-256")
+	}
+	der, _ := encodeECDSASignature(pub, r, s, ECDSAFormatDER)
+	if ok, _ := verifyECC(pub, 0, "hello", der); ok {
+		t.Fatal("chấp nhận chữ ký DER trên SHA-256")
+	}
+
+	current, err := signECC(kv.ECDSA, "hello", ECDSAFormatLegacy, NonceRandom)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, hash := range []crypto.Hash{kv.Hash, 0} {
+		if ok, _ := verifyECC(pub, hash, "hello", current); !ok {
+			t.Fatalf("từ chối chữ ký SHA-384 (hàm băm phiên bản %v)", hash)
+		}
+	}
+}
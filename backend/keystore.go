@@ -0,0 +1,198 @@
+// keystore.go
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// Tham số scrypt dùng để dẫn xuất khóa mã hóa keystore từ passphrase
+const (
+	keystoreScryptN = 1 << 15
+	keystoreScryptR = 8
+	keystoreScryptP = 1
+)
+
+// Thông tin KDF lưu trong meta.json của keystore
+type keystoreMeta struct {
+	Version int    `json:"version"`
+	KDF     string `json:"kdf"`
+	Salt    []byte `json:"salt"`
+	N       int    `json:"n"`
+	R       int    `json:"r"`
+	P       int    `json:"p"`
+}
+
+// Phiên bản định dạng dữ liệu bên trong file khóa:
+// 1 là dữ liệu khóa thô, 2 là keyRecord dạng JSON
+const keystoreEntryVersion = 2
+
+// Một khóa đã mã hóa được lưu trên đĩa
+type keystoreEntry struct {
+	Version    int    `json:"version"`
+	Type       string `json:"type"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// Keystore lưu khóa trong một thư mục, mỗi khóa được mã hóa bằng AES-256-GCM
+type Keystore struct {
+	dir  string
+	aead cipher.AEAD
+}
+
+// Mở (hoặc tạo mới) keystore tại thư mục dir
+func openKeystore(dir, passphrase string) (*Keystore, error) {
+	if passphrase == "" {
+		return nil, errors.New("chưa đặt passphrase cho keystore (KEYSTORE_PASSPHRASE)")
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("không thể tạo thư mục keystore: %v", err)
+	}
+
+	meta, err := loadKeystoreMeta(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := scrypt.Key([]byte(passphrase), meta.Salt, meta.N, meta.R, meta.P, 32)
+	if err != nil {
+		return nil, fmt.Errorf("không thể dẫn xuất khóa keystore: %v", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Keystore{dir: dir, aead: aead}, nil
+}
+
+// Đọc meta.json, tạo mới với salt ngẫu nhiên nếu chưa có
+func loadKeystoreMeta(dir string) (*keystoreMeta, error) {
+	path := filepath.Join(dir, "meta.json")
+	data, err := os.ReadFile(path)
+	if err == nil {
+		var meta keystoreMeta
+		if err := json.Unmarshal(data, &meta); err != nil {
+			return nil, fmt.Errorf("meta.json không hợp lệ: %v", err)
+		}
+		if meta.KDF != "scrypt" {
+			return nil, fmt.Errorf("KDF không được hỗ trợ: %s", meta.KDF)
+		}
+		return &meta, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	meta := &keystoreMeta{
+		Version: 1,
+		KDF:     "scrypt",
+		Salt:    make([]byte, 16),
+		N:       keystoreScryptN,
+		R:       keystoreScryptR,
+		P:       keystoreScryptP,
+	}
+	if _, err := rand.Read(meta.Salt); err != nil {
+		return nil, err
+	}
+	data, err = json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := writeFileAtomic(path, data); err != nil {
+		return nil, err
+	}
+	return meta, nil
+}
+
+// Ghi file qua một file tạm rồi đổi tên để tránh file bị ghi dở
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Mã hóa và lưu dữ liệu khóa với tên name
+func (ks *Keystore) save(name, keyType string, material []byte) error {
+	nonce := make([]byte, ks.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	entry := keystoreEntry{
+		Version:    keystoreEntryVersion,
+		Type:       keyType,
+		Nonce:      nonce,
+		Ciphertext: ks.aead.Seal(nil, nonce, material, []byte(name+"|"+keyType)),
+	}
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(ks.dir, name+".key"), data)
+}
+
+// Đọc và giải mã khóa có tên name, trả về os.ErrNotExist nếu chưa có
+func (ks *Keystore) load(name string) (*keystoreEntry, []byte, error) {
+	data, err := os.ReadFile(filepath.Join(ks.dir, name+".key"))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var entry keystoreEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, nil, fmt.Errorf("file khóa %s không hợp lệ: %v", name, err)
+	}
+	material, err := ks.aead.Open(nil, entry.Nonce, entry.Ciphertext, []byte(name+"|"+entry.Type))
+	if err != nil {
+		return nil, nil, fmt.Errorf("không thể giải mã khóa %s (sai passphrase?)", name)
+	}
+	return &entry, material, nil
+}
+
+// Xóa file khóa có tên name
+func (ks *Keystore) delete(name string) error {
+	return os.Remove(filepath.Join(ks.dir, name+".key"))
+}
+
+// Liệt kê tên các khóa có trong keystore
+func (ks *Keystore) list() ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(ks.dir, "*.key"))
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(files))
+	for i, file := range files {
+		names[i] = strings.TrimSuffix(filepath.Base(file), ".key")
+	}
+	return names, nil
+}
@@ -2,110 +2,254 @@
 package main
 
 import (
+	"crypto/ed25519"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"os"
 	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
 )
 
 type EncryptRequest struct {
 	Algorithm string `json:"algorithm"`
+	KeyID     string `json:"keyId,omitempty"`
 	Message   string `json:"message"`
+	Trace     bool   `json:"trace,omitempty"`
+
+	// Dữ liệu liên kết được xác thực nhưng không mã hóa (AES-GCM, CHACHA20-POLY1305, XCHACHA20-POLY1305)
+	AssociatedData string `json:"associatedData,omitempty"`
 }
 
 type DecryptRequest struct {
-	Algorithm       string `json:"algorithm"`
+	Algorithm        string `json:"algorithm"`
+	KeyID            string `json:"keyId,omitempty"`
 	EncryptedMessage string `json:"encryptedMessage"`
+	Trace            bool   `json:"trace,omitempty"`
+
+	// Phải trùng với dữ liệu liên kết khi mã hóa
+	AssociatedData string `json:"associatedData,omitempty"`
 }
 
 type EncryptResponse struct {
-	EncryptedMessage string `json:"encryptedMessage"`
+	EncryptedMessage string      `json:"encryptedMessage"`
+	KeyID            string      `json:"keyId"`
+	KeyVersion       int         `json:"keyVersion"`
+	Trace            []TraceStep `json:"trace,omitempty"`
 }
 
 type DecryptResponse struct {
-	DecryptedMessage string `json:"decryptedMessage"`
+	DecryptedMessage string      `json:"decryptedMessage"`
+	KeyID            string      `json:"keyId"`
+	KeyVersion       int         `json:"keyVersion"`
+	Trace            []TraceStep `json:"trace,omitempty"`
 }
 
 // Struct cho yêu cầu và phản hồi chữ ký số
 type SignRequest struct {
 	Algorithm string `json:"algorithm"`
+	KeyID     string `json:"keyId,omitempty"`
 	Message   string `json:"message"`
+	Context   string `json:"context,omitempty"`
+	Trace     bool   `json:"trace,omitempty"`
+
+	// Lược đồ chữ ký RSA: "pkcs1v15" (mặc định) hoặc "pss", hàm băm SHA-256/384/512,
+	// độ dài salt PSS "auto" hoặc "equals-hash". /verify đọc lược đồ từ chữ ký.
+	// Với ELGAMAL: "hashed" (mặc định), "subgroup" (kiểu DSA) hoặc "textbook" (không an toàn).
+	Scheme     string `json:"scheme,omitempty"`
+	Hash       string `json:"hash,omitempty"`
+	SaltLength string `json:"saltLength,omitempty"`
+
+	// Định dạng chữ ký ECDSA: "legacy" (mặc định), "der" hoặc "p1363".
+	// "jws" (ECDSA, ED25519, SECP256K1): chữ ký JWS base64url không có tiền tố phiên bản.
+	Format string `json:"format,omitempty"`
+
+	// Cách chọn nonce k cho ECC/ECDSA, EC-ECDSA, DSA và ELGAMAL: "random" (mặc định)
+	// hoặc "rfc6979" (xác định, cùng khóa và thông điệp cho cùng chữ ký)
+	Nonce string `json:"nonce,omitempty"`
+
+	// Dữ liệu có cấu trúc EIP-712 (types, primaryType, domain, message)
+	TypedData json.RawMessage `json:"typedData,omitempty"`
 }
 
 type SignResponse struct {
-	Signature string `json:"signature"`
+	Signature  string      `json:"signature"`
+	KeyID      string      `json:"keyId"`
+	KeyVersion int         `json:"keyVersion"`
+	Kid        string      `json:"kid,omitempty"`
+	Alg        string      `json:"alg,omitempty"`
+	Address    string      `json:"address,omitempty"`
+	Warning    string      `json:"warning,omitempty"`
+	Trace      []TraceStep `json:"trace,omitempty"`
 }
 
 type VerifyRequest struct {
 	Algorithm string `json:"algorithm"`
+	KeyID     string `json:"keyId,omitempty"`
 	Message   string `json:"message"`
 	Signature string `json:"signature"`
+	Context   string `json:"context,omitempty"`
+	Trace     bool   `json:"trace,omitempty"`
+
+	// EIP191/EIP712: xác thực theo địa chỉ người ký, không cần khóa trong registry
+	Address   string          `json:"address,omitempty"`
+	TypedData json.RawMessage `json:"typedData,omitempty"`
+
+	// SCHNORR: xác thực theo lô nhiều chữ ký trong một yêu cầu
+	Batch []SchnorrBatchEntry `json:"batch,omitempty"`
+}
+
+// Yêu cầu khôi phục người ký từ chữ ký secp256k1
+type RecoverRequest struct {
+	Algorithm string          `json:"algorithm,omitempty"`
+	Message   string          `json:"message"`
+	Signature string          `json:"signature"`
+	TypedData json.RawMessage `json:"typedData,omitempty"`
+}
+
+type RecoverResponse struct {
+	PublicKey string `json:"publicKey"`
+	Address   string `json:"address"`
 }
 
 type VerifyResponse struct {
-	IsValid bool `json:"isValid"`
+	IsValid    bool        `json:"isValid"`
+	KeyID      string      `json:"keyId"`
+	KeyVersion int         `json:"keyVersion"`
+	Address    string      `json:"address,omitempty"`
+	Warning    string      `json:"warning,omitempty"`
+	Trace      []TraceStep `json:"trace,omitempty"`
+
+	// Kết quả từng chữ ký khi xác thực theo lô thất bại
+	Results []bool `json:"results,omitempty"`
+}
+
+// Tìm khóa cho thuật toán theo keyId (hoặc khóa mặc định), ghi lỗi HTTP nếu không được
+func resolveKey(w http.ResponseWriter, keyTypes map[string]string, algorithm, keyID string) (*Key, bool) {
+	keyType, ok := keyTypes[algorithm]
+	if !ok {
+		http.Error(w, "Unsupported algorithm", http.StatusBadRequest)
+		return nil, false
+	}
+	key, err := registry.resolve(keyType, keyID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return nil, false
+	}
+	return key, true
+}
+
+// Bật chế độ trace nếu được yêu cầu, ghi lỗi HTTP nếu thuật toán không hỗ trợ
+func resolveTrace(w http.ResponseWriter, algorithms map[string]bool, algorithm string, enabled bool) (*Trace, bool) {
+	if enabled && !algorithms[algorithm] {
+		http.Error(w, "Trace is not supported for "+algorithm, http.StatusBadRequest)
+		return nil, false
+	}
+	return newTrace(enabled), true
 }
 
 func encryptHandler(w http.ResponseWriter, r *http.Request) {
 	var req EncryptRequest
 	json.NewDecoder(r.Body).Decode(&req)
 
-	switch strings.ToUpper(req.Algorithm) {
-	case "RSA":
-		encryptedMessage, err := encryptRSA(req.Message)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-		json.NewEncoder(w).Encode(EncryptResponse{EncryptedMessage: encryptedMessage})
+	algorithm := strings.ToUpper(req.Algorithm)
+	key, ok := resolveKey(w, encryptionKeyTypes, algorithm, req.KeyID)
+	if !ok {
+		return
+	}
+	tr, ok := resolveTrace(w, traceEncryptionAlgorithms, algorithm, req.Trace)
+	if !ok {
+		return
+	}
+
+	// Luôn mã hóa bằng phiên bản khóa mới nhất
+	kv := key.latest()
 
+	var encryptedMessage string
+	var err error
+	switch algorithm {
+	case "RSA":
+		encryptedMessage, err = encryptRSA(&kv.RSA.PublicKey, req.Message, tr)
+	case "RSA-AES-GCM":
+		encryptedMessage, err = encryptRSAHybrid(&kv.RSA.PublicKey, req.Message)
 	case "ELGAMAL":
-		encryptedMessage, err := encryptElGamalLong(req.Message)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-		json.NewEncoder(w).Encode(EncryptResponse{EncryptedMessage: encryptedMessage})
+		encryptedMessage, err = encryptElGamalLong(kv.ElGamal, req.Message, tr)
 	case "ECC":
-		encryptedMessage, err := encryptECC(req.Message)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
+		encryptedMessage, err = encryptECC(kv.ECC, req.Message, tr)
+	case "ECIES":
+		encryptedMessage, err = encryptECIES(kv.ECIES.PublicKey(), req.Message)
+	case "EC-ELGAMAL":
+		encryptedMessage, err = encryptECElGamal(kv.EC, req.Message, tr)
+	case "AES-GCM", "CHACHA20-POLY1305", "XCHACHA20-POLY1305":
+		if err = registry.countAEADEncryption(key, kv, algorithm); err == nil {
+			encryptedMessage, err = encryptAEAD(algorithm, kv.Secret, req.Message, req.AssociatedData)
 		}
-		json.NewEncoder(w).Encode(EncryptResponse{EncryptedMessage: encryptedMessage})
 	}
-	
+	if errors.Is(err, errAEADLimitReached) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(EncryptResponse{
+		EncryptedMessage: withKeyVersion(kv.Version, encryptedMessage),
+		KeyID:            key.ID,
+		KeyVersion:       kv.Version,
+		Trace:            tr.Steps(),
+	})
 }
 
 func decryptHandler(w http.ResponseWriter, r *http.Request) {
 	var req DecryptRequest
 	json.NewDecoder(r.Body).Decode(&req)
 
-	switch strings.ToUpper(req.Algorithm) {
-	case "RSA":
-		decryptedMessage, err := decryptRSA(req.EncryptedMessage)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-		json.NewEncoder(w).Encode(DecryptResponse{DecryptedMessage: decryptedMessage})
+	algorithm := strings.ToUpper(req.Algorithm)
+	key, ok := resolveKey(w, encryptionKeyTypes, algorithm, req.KeyID)
+	if !ok {
+		return
+	}
+	tr, ok := resolveTrace(w, traceEncryptionAlgorithms, algorithm, req.Trace)
+	if !ok {
+		return
+	}
 
+	// Chọn phiên bản khóa đã dùng khi mã hóa
+	kv, encryptedMessage, err := key.versionFor(req.EncryptedMessage)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	var decryptedMessage string
+	switch algorithm {
+	case "RSA":
+		decryptedMessage, err = decryptRSA(kv.RSA, encryptedMessage, tr)
+	case "RSA-AES-GCM":
+		decryptedMessage, err = decryptRSAHybrid(kv.RSA, encryptedMessage)
 	case "ELGAMAL":
-		decryptedMessage, err := decryptElGamalLong(req.EncryptedMessage)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-		json.NewEncoder(w).Encode(DecryptResponse{DecryptedMessage: decryptedMessage})
+		decryptedMessage, err = decryptElGamalLong(kv.ElGamal, encryptedMessage, tr)
 	case "ECC":
-		decryptedMessage, err := decryptECC(req.EncryptedMessage)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-		json.NewEncoder(w).Encode(DecryptResponse{DecryptedMessage: decryptedMessage})
+		decryptedMessage, err = decryptECC(kv.ECC, encryptedMessage, tr)
+	case "ECIES":
+		decryptedMessage, err = decryptECIES(kv.ECIES, encryptedMessage)
+	case "EC-ELGAMAL":
+		decryptedMessage, err = decryptECElGamal(kv.EC, encryptedMessage, tr)
+	case "AES-GCM", "CHACHA20-POLY1305", "XCHACHA20-POLY1305":
+		decryptedMessage, err = decryptAEAD(algorithm, kv.Secret, encryptedMessage, req.AssociatedData)
 	}
-	
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(DecryptResponse{DecryptedMessage: decryptedMessage, KeyID: key.ID, KeyVersion: kv.Version, Trace: tr.Steps()})
 }
 
 // Hàm xử lý tạo chữ ký số (signHandler)
@@ -117,35 +261,117 @@ func signHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var signature string
-	switch strings.ToUpper(req.Algorithm) {
-	case "RSA":
-		// Tạo chữ ký số bằng RSA
-		signature, err = signMessage(req.Message)
+	algorithm := strings.ToUpper(req.Algorithm)
+	key, ok := resolveKey(w, signatureKeyTypes, algorithm, req.KeyID)
+	if !ok {
+		return
+	}
+	tr, ok := resolveTrace(w, traceSignatureAlgorithms, algorithm, req.Trace)
+	if !ok {
+		return
+	}
+
+	// Luôn ký bằng phiên bản khóa mới nhất
+	kv := key.latest()
+
+	nonce, err := parseNonceMode(req.Nonce)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Chữ ký JWS cho công cụ JOSE: không gắn phiên bản, khóa được chọn theo kid trong JWKS
+	if strings.EqualFold(req.Format, SignatureFormatJWS) {
+		signature, err := signJWS(algorithm, kv, req.Message, nonce)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		jwk, err := publicJWK(key.Type, kv)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
+		json.NewEncoder(w).Encode(SignResponse{
+			Signature:  signature,
+			KeyID:      key.ID,
+			KeyVersion: kv.Version,
+			Kid:        jwk.Kid,
+			Alg:        jwk.Alg,
+		})
+		return
+	}
+
+	var signature, warning string
+	switch algorithm {
+	case "RSA":
+		// Tạo chữ ký số bằng RSA
+		var scheme rsaSignatureScheme
+		if scheme, err = parseRSASignatureScheme(req.Scheme, req.Hash, req.SaltLength); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		signature, err = signMessage(kv.RSA, req.Message, scheme, tr)
 	case "ELGAMAL":
 		// Tạo chữ ký số bằng Elgamal
-		signature, err = signElGamal(req.Message)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+		var scheme string
+		if scheme, err = parseElGamalScheme(req.Scheme); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
-	case "ECC":
+		if scheme == ElGamalSchemeSubgroup && kv.ElGamal.Q == nil {
+			http.Error(w, "Khóa ElGamal cũ không có bậc nhóm con q, không ký được theo lược đồ subgroup", http.StatusBadRequest)
+			return
+		}
+		if scheme == ElGamalSchemeTextbook {
+			warning = elGamalTextbookWarning
+		}
+		signature, err = signElGamal(kv.ElGamal, req.Message, scheme, nonce, tr)
+	case "ECC", "ECDSA":
 		// Tạo chữ ký số bằng ECC
-		signature, err = signECC(req.Message)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+		var format string
+		if format, err = parseECDSAFormat(req.Format); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
-	default:
-		http.Error(w, "Unsupported algorithm", http.StatusBadRequest)
+		signature, err = signECC(kv.ECDSA, req.Message, format, nonce)
+	case "EC-ECDSA":
+		// ECDSA tự cài đặt trên đường cong của khóa EC
+		signature, err = signECDSATextbook(kv.EC, req.Message, nonce, tr)
+	case "ED25519", "ED25519PH", "ED25519CTX":
+		signature, err = signEd25519(kv.Ed25519, algorithm, req.Context, req.Message)
+	case "SECP256K1":
+		signature, err = signSecp256k1(kv.Secp256k1, req.Message)
+	case "EIP191", "EIP712":
+		var hash []byte
+		if hash, err = ethereumMessageHash(algorithm, req.Message, req.TypedData); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		signature, err = signSecp256k1Hash(kv.Secp256k1, hash)
+	case "SCHNORR":
+		// Schnorr BIP-340 tự cài đặt trên secp256k1
+		signature, err = signSchnorr(kv.Schnorr, req.Message, tr)
+	case "DSA":
+		signature, err = signDSA(kv.DSA, req.Message, nonce, tr)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	json.NewEncoder(w).Encode(SignResponse{Signature: signature})
+	resp := SignResponse{
+		Signature:  withKeyVersion(kv.Version, signature),
+		KeyID:      key.ID,
+		KeyVersion: kv.Version,
+		Kid:        keyKid(key.Type, kv),
+		Warning:    warning,
+		Trace:      tr.Steps(),
+	}
+	if kv.Secp256k1 != nil {
+		resp.Address = ethereumAddress(&kv.Secp256k1.PublicKey)
+	}
+	json.NewEncoder(w).Encode(resp)
 }
 
 // Hàm xử lý xác thực chữ ký số (verifyHandler)
@@ -157,25 +383,162 @@ func verifyHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	algorithm := strings.ToUpper(req.Algorithm)
+
+	// Chữ ký kiểu ví Ethereum gửi kèm địa chỉ được xác thực theo địa chỉ đó, không cần khóa trong registry
+	if ethereumMessageAlgorithms[algorithm] && req.Address != "" {
+		if _, ok := resolveTrace(w, traceSignatureAlgorithms, algorithm, req.Trace); !ok {
+			return
+		}
+		_, signature := splitKeyVersion(req.Signature)
+		isValid, err := verifyEthereumMessage(req.Address, algorithm, req.Message, req.TypedData, signature)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(VerifyResponse{IsValid: isValid, Address: common.HexToAddress(req.Address).Hex()})
+		return
+	}
+
+	key, ok := resolveKey(w, signatureKeyTypes, algorithm, req.KeyID)
+	if !ok {
+		return
+	}
+	tr, ok := resolveTrace(w, traceSignatureAlgorithms, algorithm, req.Trace)
+	if !ok {
+		return
+	}
+
+	if len(req.Batch) > 0 {
+		verifyBatch(w, key, algorithm, req.Batch, tr)
+		return
+	}
+
+	// Chọn phiên bản khóa đã dùng khi ký
+	kv, signature, err := key.versionFor(req.Signature)
+	if err != nil {
+		json.NewEncoder(w).Encode(VerifyResponse{IsValid: false, KeyID: key.ID})
+		return
+	}
+
 	var isValid bool
-	switch strings.ToUpper(req.Algorithm) {
+	var warning string
+	switch algorithm {
 	case "RSA":
 		// Xác thực chữ ký số bằng RSA
-		isValid = verifySignature(req.Message, req.Signature)
+		isValid = verifySignature(&kv.RSA.PublicKey, req.Message, signature, tr)
 	case "ELGAMAL":
 		// Xác thực chữ ký số bằng Elgamal
-		isValid,_ = verifyElGamal(req.Message, req.Signature)
-	case "ECC":
+		isValid, _ = verifyElGamal(kv.ElGamal, req.Message, signature, tr)
+		if scheme, _, _, err := splitElGamalSignature(signature); err == nil && scheme == ElGamalSchemeTextbook {
+			warning = elGamalTextbookWarning
+		}
+	case "ECC", "ECDSA":
 		// Xác thực chữ ký số bằng ECC
-		isValid,_ = verifyECC(req.Message, req.Signature)
+		isValid, _ = verifyECC(&kv.ECDSA.PublicKey, kv.Hash, req.Message, signature)
+	case "EC-ECDSA":
+		isValid, _ = verifyECDSATextbook(kv.EC, req.Message, signature, tr)
+	case "ED25519", "ED25519PH", "ED25519CTX":
+		isValid, _ = verifyEd25519(kv.Ed25519.Public().(ed25519.PublicKey), algorithm, req.Context, req.Message, signature)
+	case "SECP256K1":
+		isValid, _ = verifySecp256k1(&kv.Secp256k1.PublicKey, req.Message, signature)
+	case "EIP191", "EIP712":
+		// Xác thực theo địa chỉ của khóa
+		isValid, _ = verifyEthereumMessage(ethereumAddress(&kv.Secp256k1.PublicKey), algorithm, req.Message, req.TypedData, signature)
+	case "SCHNORR":
+		isValid, _ = verifySchnorr(schnorrPublicKey(kv.Schnorr), req.Message, signature, tr)
+	case "DSA":
+		isValid, _ = verifyDSA(kv.DSA, req.Message, signature, tr)
+	}
+
+	resp := VerifyResponse{IsValid: isValid, KeyID: key.ID, KeyVersion: kv.Version, Warning: warning, Trace: tr.Steps()}
+	if kv.Secp256k1 != nil {
+		resp.Address = ethereumAddress(&kv.Secp256k1.PublicKey)
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+
+// Xác thực theo lô các chữ ký Schnorr. Mỗi chữ ký dùng khóa công khai x-only đi kèm,
+// nếu không có thì dùng phiên bản khóa đã ký của key.
+func verifyBatch(w http.ResponseWriter, key *Key, algorithm string, batch []SchnorrBatchEntry, tr *Trace) {
+	if algorithm != "SCHNORR" {
+		http.Error(w, "Batch verification is only supported for SCHNORR", http.StatusBadRequest)
+		return
+	}
+
+	publicKeys := make([][]byte, len(batch))
+	for i := range batch {
+		if batch[i].PublicKey != "" {
+			publicKey, err := hex.DecodeString(batch[i].PublicKey)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("publicKey của chữ ký %d không hợp lệ", i+1), http.StatusBadRequest)
+				return
+			}
+			publicKeys[i] = publicKey
+			_, batch[i].Signature = splitKeyVersion(batch[i].Signature)
+			continue
+		}
+		// Phiên bản khóa không tồn tại: khóa công khai rỗng, chữ ký được coi là không hợp lệ
+		if kv, signature, err := key.versionFor(batch[i].Signature); err == nil {
+			publicKeys[i] = schnorrPublicKey(kv.Schnorr)
+			batch[i].Signature = signature
+		}
+	}
+
+	isValid, err := verifySchnorrBatch(publicKeys, batch, tr)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp := VerifyResponse{IsValid: isValid, KeyID: key.ID, Trace: tr.Steps()}
+	if !isValid {
+		// Lô không hợp lệ: xác thực từng chữ ký để chỉ ra chữ ký sai
+		resp.Results = make([]bool, len(batch))
+		for i, entry := range batch {
+			resp.Results[i], _ = verifySchnorr(publicKeys[i], entry.Message, entry.Signature, nil)
+		}
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+// Hàm xử lý khôi phục khóa công khai và địa chỉ Ethereum của người ký (recoverHandler)
+func recoverHandler(w http.ResponseWriter, r *http.Request) {
+	var req RecoverRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	var hash []byte
+	switch algorithm := strings.ToUpper(req.Algorithm); {
+	case algorithm == "" || algorithm == "SECP256K1":
+		hash = secp256k1Hash(req.Message)
+	case ethereumMessageAlgorithms[algorithm]:
+		var err error
+		if hash, err = ethereumMessageHash(algorithm, req.Message, req.TypedData); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 	default:
 		http.Error(w, "Unsupported algorithm", http.StatusBadRequest)
 		return
 	}
 
-	json.NewEncoder(w).Encode(VerifyResponse{IsValid: isValid})
-}
+	// Bỏ tiền tố phiên bản khóa nếu chữ ký lấy từ /sign
+	_, signature := splitKeyVersion(req.Signature)
+	publicKey, err := recoverSecp256k1(hash, signature)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
+	json.NewEncoder(w).Encode(RecoverResponse{
+		PublicKey: "0x" + hex.EncodeToString(ethcrypto.FromECDSAPub(publicKey)),
+		Address:   ethereumAddress(publicKey),
+	})
+}
 
 // Middleware xử lý CORS
 func corsMiddleware(next http.HandlerFunc) http.HandlerFunc {
@@ -196,17 +559,46 @@ func corsMiddleware(next http.HandlerFunc) http.HandlerFunc {
 }
 
 func main() {
-	generateRSAKeys(2048)
-	generateRSAKeys_1(2048)
-	generateElGamalKeys(512)
-	generateECCKeys()
-	generateECCKey()
+	// Nạp khóa từ keystore trên đĩa, chỉ sinh khóa mới khi chưa có
+	ks, err := openKeystore(getEnv("KEYSTORE_DIR", "keystore"), os.Getenv("KEYSTORE_PASSPHRASE"))
+	if err != nil {
+		fmt.Println("Error opening keystore:", err)
+		os.Exit(1)
+	}
+	if err := loadCustomCurves(ks.dir); err != nil {
+		fmt.Println("Error loading curves:", err)
+		os.Exit(1)
+	}
+	registry, err = openKeyRegistry(ks)
+	if err != nil {
+		fmt.Println("Error loading keys:", err)
+		os.Exit(1)
+	}
+
+	// Sinh sẵn số nguyên tố an toàn cho khóa ElGamal kích thước mặc định
+	startSafePrimePool(512, 2)
 
 	http.HandleFunc("/encrypt", corsMiddleware(encryptHandler))
     http.HandleFunc("/decrypt", corsMiddleware(decryptHandler))
 
 	http.HandleFunc("/sign", corsMiddleware(signHandler)) 
 	http.HandleFunc("/verify", corsMiddleware(verifyHandler)) 
+	http.HandleFunc("/recover", corsMiddleware(recoverHandler))
+
+	http.HandleFunc("/keys", corsMiddleware(keysHandler))
+	http.HandleFunc("/keys/import", corsMiddleware(importKeyHandler))
+	http.HandleFunc("/keys/{id}", corsMiddleware(keyHandler))
+	http.HandleFunc("/keys/{id}/export", corsMiddleware(exportKeyHandler))
+	http.HandleFunc("/keys/{id}/jwk", corsMiddleware(keyJWKHandler))
+	http.HandleFunc("/keys/{id}/rotate", corsMiddleware(rotateKeyHandler))
+	http.HandleFunc("/.well-known/jwks.json", corsMiddleware(jwksHandler))
+	http.HandleFunc("/keys/{id}/disable", corsMiddleware(keyStatusHandler(true)))
+	http.HandleFunc("/keys/{id}/enable", corsMiddleware(keyStatusHandler(false)))
+
+	http.HandleFunc("/curves", corsMiddleware(curvesHandler))
+	http.HandleFunc("/curves/validate", corsMiddleware(validateCurveHandler))
+	http.HandleFunc("/curves/explore", corsMiddleware(exploreCurveHandler))
+	http.HandleFunc("/curves/{name}", corsMiddleware(curveHandler))
 
 	fmt.Println("Server is running on http://localhost:8080")
 	http.ListenAndServe(":8080", nil)
@@ -0,0 +1,158 @@
+// pem.go
+package main
+
+import (
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Các định dạng PEM chứa khóa bí mật
+var privateKeyFormats = map[string]bool{"pkcs1": true, "sec1": true, "pkcs8": true}
+
+// Xuất khóa dưới dạng PEM theo định dạng:
+//   - pkix:         khóa công khai SubjectPublicKeyInfo ("PUBLIC KEY")
+//   - pkcs1-public: khóa công khai RSA PKCS#1 ("RSA PUBLIC KEY")
+//   - pkcs1:        khóa bí mật RSA PKCS#1 ("RSA PRIVATE KEY")
+//   - sec1:         khóa bí mật EC theo SEC 1 ("EC PRIVATE KEY")
+//   - pkcs8:        khóa bí mật PKCS#8 ("PRIVATE KEY")
+func exportKeyPEM(key *KeyVersion, format string) (string, error) {
+	var private any
+	switch {
+	case key.RSA != nil:
+		private = key.RSA
+	case key.ECDSA != nil:
+		private = key.ECDSA
+	case key.ECIES != nil:
+		private = key.ECIES
+	case key.Ed25519 != nil:
+		private = key.Ed25519
+	default:
+		return "", errors.New("chỉ hỗ trợ xuất PEM cho khóa RSA, ECDSA, ECIES và Ed25519")
+	}
+
+	var block *pem.Block
+	switch strings.ToLower(format) {
+	case "pkix":
+		der, err := x509.MarshalPKIXPublicKey(publicKeyOf(private))
+		if err != nil {
+			return "", err
+		}
+		block = &pem.Block{Type: "PUBLIC KEY", Bytes: der}
+
+	case "pkcs1-public":
+		if key.RSA == nil {
+			return "", errors.New("định dạng pkcs1-public chỉ dùng cho khóa RSA")
+		}
+		block = &pem.Block{Type: "RSA PUBLIC KEY", Bytes: x509.MarshalPKCS1PublicKey(&key.RSA.PublicKey)}
+
+	case "pkcs1":
+		if key.RSA == nil {
+			return "", errors.New("định dạng pkcs1 chỉ dùng cho khóa RSA")
+		}
+		block = &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key.RSA)}
+
+	case "sec1":
+		if key.ECDSA == nil {
+			return "", errors.New("định dạng sec1 chỉ dùng cho khóa ECDSA")
+		}
+		der, err := x509.MarshalECPrivateKey(key.ECDSA)
+		if err != nil {
+			return "", err
+		}
+		block = &pem.Block{Type: "EC PRIVATE KEY", Bytes: der}
+
+	case "pkcs8":
+		der, err := x509.MarshalPKCS8PrivateKey(private)
+		if err != nil {
+			return "", err
+		}
+		block = &pem.Block{Type: "PRIVATE KEY", Bytes: der}
+
+	default:
+		return "", fmt.Errorf("định dạng PEM không được hỗ trợ: %s", format)
+	}
+
+	return string(pem.EncodeToMemory(block)), nil
+}
+
+// Lấy khóa công khai tương ứng với khóa bí mật
+func publicKeyOf(private any) any {
+	switch k := private.(type) {
+	case *rsa.PrivateKey:
+		return &k.PublicKey
+	case *ecdsa.PrivateKey:
+		return &k.PublicKey
+	case *ecdh.PrivateKey:
+		return k.PublicKey()
+	case ed25519.PrivateKey:
+		return k.Public()
+	}
+	return nil
+}
+
+// Đọc khóa bí mật RSA, ECDSA, Ed25519 hoặc X25519 (ECIES) từ PEM (PKCS#1, PKCS#8 hoặc SEC 1), trả về loại khóa
+func importKeyPEM(data string) (string, *KeyVersion, error) {
+	rest := []byte(data)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			return "", nil, errors.New("không tìm thấy khóa bí mật trong PEM")
+		}
+
+		var parsed any
+		var err error
+		switch block.Type {
+		case "RSA PRIVATE KEY":
+			parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+		case "EC PRIVATE KEY":
+			parsed, err = x509.ParseECPrivateKey(block.Bytes)
+		case "PRIVATE KEY":
+			parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+		case "EC PARAMETERS":
+			// openssl ecparam -genkey ghi tham số đường cong trước khóa
+			continue
+		case "PUBLIC KEY", "RSA PUBLIC KEY":
+			return "", nil, errors.New("chỉ hỗ trợ nhập khóa bí mật")
+		case "ENCRYPTED PRIVATE KEY":
+			return "", nil, errors.New("không hỗ trợ khóa PKCS#8 được mã hóa, hãy giải mã trước khi nhập")
+		default:
+			return "", nil, fmt.Errorf("loại PEM không được hỗ trợ: %s", block.Type)
+		}
+		if err != nil {
+			return "", nil, err
+		}
+
+		switch k := parsed.(type) {
+		case *rsa.PrivateKey:
+			if k.N.BitLen() < 1024 {
+				return "", nil, errors.New("khóa RSA phải có ít nhất 1024 bit")
+			}
+			if err := k.Validate(); err != nil {
+				return "", nil, err
+			}
+			return KeyTypeRSA, &KeyVersion{RSA: k}, nil
+		case *ecdsa.PrivateKey:
+			if _, ok := ecdsaCurves[k.Curve.Params().Name]; !ok {
+				return "", nil, fmt.Errorf("đường cong không được hỗ trợ: %s", k.Curve.Params().Name)
+			}
+			return KeyTypeECDSA, &KeyVersion{ECDSA: k}, nil
+		case *ecdh.PrivateKey:
+			if k.Curve() != ecdh.X25519() {
+				return "", nil, fmt.Errorf("loại khóa không được hỗ trợ: %T", parsed)
+			}
+			return KeyTypeECIES, &KeyVersion{ECIES: k}, nil
+		case ed25519.PrivateKey:
+			return KeyTypeEd25519, &KeyVersion{Ed25519: k}, nil
+		default:
+			return "", nil, fmt.Errorf("loại khóa không được hỗ trợ: %T", parsed)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+// rfc6979.go
+package main
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Cách chọn nonce k khi ký ECDSA, DSA và ElGamal
+const (
+	NonceRandom  = "random"  // k ngẫu nhiên từ crypto/rand (mặc định)
+	NonceRFC6979 = "rfc6979" // k xác định từ khóa bí mật và giá trị băm theo RFC 6979, chữ ký tái lập được
+)
+
+// Chuẩn hóa cách chọn nonce trong yêu cầu ký
+func parseNonceMode(mode string) (string, error) {
+	switch m := strings.ToLower(mode); m {
+	case "":
+		return NonceRandom, nil
+	case NonceRandom, NonceRFC6979:
+		return m, nil
+	}
+	return "", fmt.Errorf("cách chọn nonce không được hỗ trợ: %s", mode)
+}
+
+// Hàm trả về lần lượt các nonce k trong [1, q-1]; lần gọi sau dùng khi k trước bị loại (r = 0, s = 0, ...)
+type nonceFunc func() (*big.Int, error)
+
+// Tạo nguồn nonce cho khóa bí mật x trong nhóm bậc q; h1 là giá trị băm của thông điệp
+// bằng hàm băm hash (chỉ dùng với RFC 6979)
+func newNonceFunc(mode string, q, x *big.Int, hash crypto.Hash, h1 []byte) nonceFunc {
+	if mode == NonceRFC6979 {
+		return rfc6979Nonces(q, x, hash, h1)
+	}
+	return func() (*big.Int, error) {
+		return randScalar(q)
+	}
+}
+
+// bits2int (RFC 6979, 2.3.2): chuỗi bit thành số nguyên, giữ qlen bit bên trái
+func bitsToInt(b []byte, qlen int) *big.Int {
+	v := new(big.Int).SetBytes(b)
+	if excess := len(b)*8 - qlen; excess > 0 {
+		v.Rsh(v, uint(excess))
+	}
+	return v
+}
+
+// Sinh nonce theo RFC 6979, 3.2 với HMAC dùng hàm băm hash
+func rfc6979Nonces(q, x *big.Int, hash crypto.Hash, h1 []byte) nonceFunc {
+	qlen := q.BitLen()
+	rlen := (qlen + 7) / 8
+
+	// int2octets(x) và bits2octets(h1) = int2octets(bits2int(h1) mod q)
+	z := bitsToInt(h1, qlen)
+	z.Mod(z, q)
+	seed := append(x.FillBytes(make([]byte, rlen)), z.FillBytes(make([]byte, rlen))...)
+
+	mac := func(key []byte, data ...[]byte) []byte {
+		m := hmac.New(hash.New, key)
+		for _, d := range data {
+			m.Write(d)
+		}
+		return m.Sum(nil)
+	}
+
+	// b-g: V = 0x01 0x01 ..., K = 0x00 0x00 ..., rồi trộn x và h1 vào K, V hai lần
+	v := make([]byte, hash.Size())
+	for i := range v {
+		v[i] = 0x01
+	}
+	k := make([]byte, hash.Size())
+	k = mac(k, v, []byte{0x00}, seed)
+	v = mac(k, v)
+	k = mac(k, v, []byte{0x01}, seed)
+	v = mac(k, v)
+
+	first := true
+	return func() (*big.Int, error) {
+		for {
+			// h.3: khi nonce trước bị loại, cập nhật K = HMAC_K(V || 0x00), V = HMAC_K(V)
+			if !first {
+				k = mac(k, v, []byte{0x00})
+				v = mac(k, v)
+			}
+			first = false
+
+			// h.2: T = V_1 || V_2 || ... đủ qlen bit, k = bits2int(T)
+			var t []byte
+			for len(t) < rlen {
+				v = mac(k, v)
+				t = append(t, v...)
+			}
+			nonce := bitsToInt(t, qlen)
+			if nonce.Sign() > 0 && nonce.Cmp(q) < 0 {
+				return nonce, nil
+			}
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/x509"
+	"encoding/hex"
+	"math/big"
+	"testing"
+)
+
+// Khóa ECDSA từ khóa bí mật d (hex) trên đường cong curveName
+func testECDSAKey(t *testing.T, curveName, d string) *ecdsa.PrivateKey {
+	t.Helper()
+	b, err := hex.DecodeString(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	priv, err := ecdsaCurves[curveName].ECDH.NewPrivateKey(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed.(*ecdsa.PrivateKey)
+}
+
+// RFC 6979 A.2.5, A.2.6, A.2.7: ECDSA với hàm băm đi kèm đường cong
+var rfc6979ECDSAVectors = []struct {
+	curve, d, message, r, s string
+}{
+	{"P-256", "C9AFA9D845BA75166B5C215767B1D6934E50C3DB36E89B127B8A622B120F6721", "sample",
+		"EFD48B2AACB6A8FD1140DD9CD45E81D69D2C877B56AAF991C34D0EA84EAF3716",
+		"F7CB1C942D657C41D436C7A1B6E29F65F3E900DBB9AFF4064DC4AB2F843ACDA8"},
+	{"P-256", "C9AFA9D845BA75166B5C215767B1D6934E50C3DB36E89B127B8A622B120F6721", "test",
+		"F1ABB023518351CD71D881567B1EA663ED3EFCF6C5132B354F28D3B0B7D38367",
+		"019F4113742A2B14BD25926B49C649155F267E60D3814B4C0CC84250E46F0083"},
+	{"P-384", "6B9D3DAD2E1B8C1C05B19875B6659F4DE23C3B667BF297BA9AA47740787137D896D5724E4C70A825F872C9EA60D2EDF5", "sample",
+		"94EDBB92A5ECB8AAD4736E56C691916B3F88140666CE9FA73D64C4EA95AD133C81A648152E44ACF96E36DD1E80FABE46",
+		"99EF4AEB15F178CEA1FE40DB2603138F130E740A19624526203B6351D0A3A94FA329C145786E679E7B82C71A38628AC8"},
+	{"P-521", "00FAD06DAA62BA3B25D2FB40133DA757205DE67F5BB0018FEE8C86E1B68C7E75CAA896EB32F1F47C70855836A6D16FCC1466F6D8FBEC67DB89EC0C08B0E996B83538", "sample",
+		"00C328FAFCBD79DD77850370C46325D987CB525569FB63C5D3BC53950E6D4C5F174E25A1EE9017B5D450606ADD152B534931D7D4E8455CC91F9B15BF05EC36E377FA",
+		"00617CCE7CF5064806C467F678D3B4080D6F1CC50AF26CA209417308281B68AF282623EAA63E5B5C0723D8B8C37FF0777B1A20F8CCB1DCCC43997F1EE0E44DA4A67A"},
+	{"P-521", "00FAD06DAA62BA3B25D2FB40133DA757205DE67F5BB0018FEE8C86E1B68C7E75CAA896EB32F1F47C70855836A6D16FCC1466F6D8FBEC67DB89EC0C08B0E996B83538", "test",
+		"013E99020ABF5CEE7525D16B69B229652AB6BDF2AFFCAEF38773B4B7D08725F10CDB93482FDCC54EDCEE91ECA4166B2A7C6265EF0CE2BD7051B7CEF945BABD47EE6D",
+		"01FBD0013C674AA79CB39849527916CE301C66EA7CE8B80682786AD60F98F7E78A19CA69EFF5C57400E3B3A0AD66CE0978214D13BAF4E9AC60752F7B155E2DE4DCE3"},
+}
+
+func TestRFC6979ECDSAVectors(t *testing.T) {
+	for _, v := range rfc6979ECDSAVectors {
+		priv := testECDSAKey(t, v.curve, v.d)
+		signature, err := signECC(priv, v.message, ECDSAFormatDER, NonceRFC6979)
+		if err != nil {
+			t.Fatalf("%s %q: %v", v.curve, v.message, err)
+		}
+		r, s, _, err := decodeECDSASignature(&priv.PublicKey, signature)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, _ := parseHexInts(v.r, v.s)
+		if r.Cmp(want[0]) != 0 || s.Cmp(want[1]) != 0 {
+			t.Errorf("%s %q: r = %X, s = %X", v.curve, v.message, r, s)
+		}
+		if ok, err := verifyECC(&priv.PublicKey, ecdsaHash(&priv.PublicKey), v.message, signature); !ok || err != nil {
+			t.Errorf("%s %q: chữ ký không hợp lệ: %v", v.curve, v.message, err)
+		}
+	}
+}
+
+// Khóa DSA 2048/256 của RFC 6979 A.2.2
+var rfc6979DSA2048 = struct{ p, q, g, x, y string }{
+	p: "9DB6FB5951B66BB6FE1E140F1D2CE5502374161FD6538DF1648218642F0B5C48" +
+		"C8F7A41AADFA187324B87674FA1822B00F1ECF8136943D7C55757264E5A1A44F" +
+		"FE012E9936E00C1D3E9310B01C7D179805D3058B2A9F4BB6F9716BFE6117C6B5" +
+		"B3CC4D9BE341104AD4A80AD6C94E005F4B993E14F091EB51743BF33050C38DE2" +
+		"35567E1B34C3D6A5C0CEAA1A0F368213C3D19843D0B4B09DCB9FC72D39C8DE41" +
+		"F1BF14D4BB4563CA28371621CAD3324B6A2D392145BEBFAC748805236F5CA2FE" +
+		"92B871CD8F9C36D3292B5509CA8CAA77A2ADFC7BFD77DDA6F71125A7456FEA15" +
+		"3E433256A2261C6A06ED3693797E7995FAD5AABBCFBE3EDA2741E375404AE25B",
+	q: "F2C3119374CE76C9356990B465374A17F23F9ED35089BD969F61C6DDE9998C1F",
+	g: "5C7FF6B06F8F143FE8288433493E4769C4D988ACE5BE25A0E24809670716C613" +
+		"D7B0CEE6932F8FAA7C44D2CB24523DA53FBE4F6EC3595892D1AA58C4328A06C4" +
+		"6A15662E7EAA703A1DECF8BBB2D05DBE2EB956C142A338661D10461C0D135472" +
+		"085057F3494309FFA73C611F78B32ADBB5740C361C9F35BE90997DB2014E2EF5" +
+		"AA61782F52ABEB8BD6432C4DD097BC5423B285DAFB60DC364E8161F4A2A35ACA" +
+		"3A10B1C4D203CC76A470A33AFDCBDD92959859ABD8B56E1725252D78EAC66E71" +
+		"BA9AE3F1DD2487199874393CD4D832186800654760E1E34C09E4D155179F9EC0" +
+		"DC4473F996BDCE6EED1CABED8B6F116F7AD9CF505DF0F998E34AB27514B0FFE7",
+	x: "69C7548C21D0DFEA6B9A51C9EAD4E27C33D3B3F180316E5BCAB92C933F0E4DBC",
+	y: "667098C654426C78D7F8201EAC6C203EF030D43605032C2F1FA937E5237DBD94" +
+		"9F34A0A2564FE126DC8B715C5141802CE0979C8246463C40E6B6BDAA2513FA61" +
+		"1728716C2E4FD53BC95B89E69949D96512E873B9C8F8DFD499CC312882561ADE" +
+		"CB31F658E934C0C197F2C4D96B05CBAD67381E7B768891E4DA3843D24D94CDFB" +
+		"5126E9B8BF21E8358EE0E0A30EF13FD6A664C0DCE3731F7FB49A4845A4FD8254" +
+		"687972A2D382599C9BAC4E0ED7998193078913032558134976410B89D2C171D1" +
+		"23AC35FD977219597AA7D15C1A9A428E59194F75C721EBCBCFAE44696A499AFA" +
+		"74E04299F132026601638CB87AB79190D4A0986315DA8EEC6561C938996BEADF",
+}
+
+func TestRFC6979DSAVector(t *testing.T) {
+	values, err := parseHexInts(rfc6979DSA2048.p, rfc6979DSA2048.q, rfc6979DSA2048.g, rfc6979DSA2048.x, rfc6979DSA2048.y)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := &DSAKey{P: values[0], Q: values[1], G: values[2], X: values[3], Y: values[4]}
+
+	// A.2.2, SHA-256, thông điệp "sample"
+	signature, err := signDSA(key, "sample", NonceRFC6979, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, s, err := decodeHexSignaturePair(signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := parseHexInts(
+		"EACE8BDBBE353C432A795D9EC556C6D021F7A03F42C36E9BC87E4AC7932CC809",
+		"7081E175455F9247B812B74583E9E94F9EA79BD640DC962533B0680793A38D53")
+	if r.Cmp(want[0]) != 0 || s.Cmp(want[1]) != 0 {
+		t.Fatalf("r = %X, s = %X", r, s)
+	}
+	if ok, err := verifyDSA(key, "sample", signature, nil); !ok || err != nil {
+		t.Fatalf("chữ ký không hợp lệ: %v", err)
+	}
+}
+
+func TestBitsToInt(t *testing.T) {
+	b := []byte{0xff, 0x01}
+	if v := bitsToInt(b, 16); v.Int64() != 0xff01 {
+		t.Errorf("bitsToInt(qlen = 16) = %x", v)
+	}
+	if v := bitsToInt(b, 12); v.Int64() != 0xff0 {
+		t.Errorf("bitsToInt(qlen = 12) = %x", v)
+	}
+	if v := bitsToInt(b, 24); v.Cmp(big.NewInt(0xff01)) != 0 {
+		t.Errorf("bitsToInt(qlen = 24) = %x", v)
+	}
+}
+
+func TestParseNonceMode(t *testing.T) {
+	for in, want := range map[string]string{"": NonceRandom, "random": NonceRandom, "RFC6979": NonceRFC6979} {
+		if got, err := parseNonceMode(in); err != nil || got != want {
+			t.Errorf("parseNonceMode(%q) = %q, %v", in, got, err)
+		}
+	}
+	if _, err := parseNonceMode("fixed"); err == nil {
+		t.Error("chấp nhận cách chọn nonce không hỗ trợ")
+	}
+}
@@ -1,18 +1,18 @@
 package main
 
 import (
+	"crypto/aes"
+	"crypto/cipher"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"math/big"
 )
 
-var rsaPrivateKey *rsa.PrivateKey
-var rsaPublicKey *rsa.PublicKey
-
 // Hàm tính Ước chung lớn nhất (GCD)
 func gcd(a, b *big.Int) *big.Int {
 	zero := big.NewInt(0)
@@ -107,16 +107,8 @@ func randPrime(bits int) (*big.Int, error) {
 }
 
 // Tạo cặp khóa RSA
-func generateRSAKeys(bits int) {
-	var err error
-	rsaPrivateKey, err = rsa.GenerateKey(rand.Reader, bits)
-	if err != nil {
-		fmt.Println("Error generating RSA keys:", err)
-		return
-	}
-	rsaPublicKey = &rsaPrivateKey.PublicKey
-	println("rsaPrivateKey: ", rsaPrivateKey)
-	println( "rsaPublicKey: ",rsaPublicKey)
+func generateRSAKeys(bits int) (*rsa.PrivateKey, error) {
+	return rsa.GenerateKey(rand.Reader, bits)
 }
 
 // Chia thông điệp thành các khối nhỏ
@@ -135,10 +127,13 @@ func splitRSAMessage(message []byte, blockSize int) [][]byte {
 }
 
 // Mã hóa RSA
-func encryptRSA(message string) (string, error) {
+func encryptRSA(rsaPublicKey *rsa.PublicKey, message string, tr *Trace) (string, error) {
 	if rsaPublicKey == nil {
         return "nil", fmt.Errorf("public key is nil")
     }
+	if tr != nil {
+		return encryptRSATrace(rsaPublicKey, message, tr)
+	}
 	blockSize := rsaPublicKey.Size() - 2*sha256.Size - 2 
 	blocks := splitRSAMessage([]byte(message), blockSize)
 
@@ -166,7 +161,11 @@ func encryptRSA(message string) (string, error) {
 }
 
 // Giải mã RSA
-func decryptRSA(encryptedMessage string) (string, error) {
+func decryptRSA(rsaPrivateKey *rsa.PrivateKey, encryptedMessage string, tr *Trace) (string, error) {
+	if tr != nil {
+		return decryptRSATrace(rsaPrivateKey, encryptedMessage, tr)
+	}
+
 	decodedMessage, _ := base64.StdEncoding.DecodeString(encryptedMessage)
 
 	var encryptedBlocks []string
@@ -194,27 +193,110 @@ func decryptRSA(encryptedMessage string) (string, error) {
 	return string(decryptedMessage), nil
 }
 
-// Tạo chữ ký số (Digital Signature)
-func signMessage(message string) (string, error) {
+// Nhãn OAEP của khóa AES được bọc trong chế độ mã hóa lai
+var rsaHybridLabel = []byte("RSA-AES-GCM")
+
+// Mã hóa lai RSA: khóa AES-256 ngẫu nhiên được bọc bằng RSA-OAEP (SHA-256),
+// thông điệp được mã hóa và xác thực bằng AES-256-GCM với khóa đã bọc làm dữ liệu liên kết.
+// Bản mã: base64(khóa đã bọc || nonce || ciphertext || tag)
+func encryptRSAHybrid(rsaPublicKey *rsa.PublicKey, message string) (string, error) {
+	if rsaPublicKey == nil {
+		return "", fmt.Errorf("public key is nil")
+	}
 
-	hashed := sha256.Sum256([]byte(message))
+	aesKey := make([]byte, 32)
+	if _, err := rand.Read(aesKey); err != nil {
+		return "", err
+	}
+	wrappedKey, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, rsaPublicKey, aesKey, rsaHybridLabel)
+	if err != nil {
+		return "", err
+	}
 
-	signature, err := rsa.SignPKCS1v15(rand.Reader, rsaPrivateKey, 0, hashed[:])
+	block, err := aes.NewCipher(aesKey)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCM(block)
 	if err != nil {
 		return "", err
 	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	out := append(wrappedKey, nonce...)
+	out = gcm.Seal(out, nonce, []byte(message), wrappedKey)
+	return base64.StdEncoding.EncodeToString(out), nil
+}
+
+// Giải mã lai RSA: mở khóa AES bằng RSA-OAEP rồi giải mã và xác thực bằng AES-256-GCM
+func decryptRSAHybrid(rsaPrivateKey *rsa.PrivateKey, encryptedMessage string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(encryptedMessage)
+	if err != nil {
+		return "", errors.New("sai định dạng bản mã")
+	}
+
+	keySize := rsaPrivateKey.Size()
+	if len(data) < keySize+12+16 {
+		return "", errors.New("bản mã quá ngắn")
+	}
+	wrappedKey, nonce, ciphertext := data[:keySize], data[keySize:keySize+12], data[keySize+12:]
 
-	return base64.StdEncoding.EncodeToString(signature), nil
+	aesKey, err := rsa.DecryptOAEP(sha256.New(), nil, rsaPrivateKey, wrappedKey, rsaHybridLabel)
+	if err != nil {
+		return "", errors.New("không thể mở khóa AES")
+	}
+	block, err := aes.NewCipher(aesKey)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, wrappedKey)
+	if err != nil {
+		return "", errors.New("bản mã đã bị thay đổi hoặc sai khóa")
+	}
+	return string(plaintext), nil
 }
 
-// Xác thực chữ ký số (Verify Digital Signature)
-func verifySignature(message string, signature string) bool {
+// Tạo chữ ký số (Digital Signature), lược đồ được ghi ở đầu chữ ký
+func signMessage(rsaPrivateKey *rsa.PrivateKey, message string, scheme rsaSignatureScheme, tr *Trace) (string, error) {
+	var signature []byte
+	var err error
+	switch {
+	case tr != nil:
+		signature, err = signRSATrace(rsaPrivateKey, message, scheme, tr)
+	case scheme.PSS:
+		signature, err = rsa.SignPSS(rand.Reader, rsaPrivateKey, scheme.Hash, scheme.digest(message), &rsa.PSSOptions{SaltLength: scheme.SaltLength})
+	default:
+		signature, err = rsa.SignPKCS1v15(rand.Reader, rsaPrivateKey, scheme.Hash, scheme.digest(message))
+	}
+	if err != nil {
+		return "", err
+	}
 
-	hashed := sha256.Sum256([]byte(message))
+	return scheme.name() + ":" + base64.StdEncoding.EncodeToString(signature), nil
+}
 
+// Xác thực chữ ký số (Verify Digital Signature) theo lược đồ ghi trong chữ ký
+func verifySignature(rsaPublicKey *rsa.PublicKey, message string, signature string, tr *Trace) bool {
+	scheme, signature, err := splitRSASignature(signature)
+	if err != nil {
+		return false
+	}
 	signatureBytes, _ := base64.StdEncoding.DecodeString(signature)
-	err := rsa.VerifyPKCS1v15(rsaPublicKey, 0, hashed[:], signatureBytes)
-	return err == nil
+
+	if tr != nil {
+		return verifyRSATrace(rsaPublicKey, message, signatureBytes, scheme, tr)
+	}
+	if scheme.PSS {
+		return rsa.VerifyPSS(rsaPublicKey, scheme.Hash, scheme.digest(message), signatureBytes, &rsa.PSSOptions{SaltLength: scheme.SaltLength}) == nil
+	}
+	return rsa.VerifyPKCS1v15(rsaPublicKey, scheme.Hash, scheme.digest(message), signatureBytes) == nil
 }
 
 // func main() {
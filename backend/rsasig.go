@@ -0,0 +1,120 @@
+// rsasig.go
+package main
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Lược đồ chữ ký RSA: PKCS#1 v1.5 hoặc PSS với hàm băm SHA-256/384/512.
+// Hash = 0 là chữ ký cũ (trước khi có lược đồ): PKCS#1 v1.5 ký trực tiếp SHA-256, không có DigestInfo.
+type rsaSignatureScheme struct {
+	PSS        bool
+	Hash       crypto.Hash
+	SaltLength int
+}
+
+var rsaSignatureHashes = map[string]crypto.Hash{
+	"sha256": crypto.SHA256,
+	"sha384": crypto.SHA384,
+	"sha512": crypto.SHA512,
+}
+
+// Tiền tố DigestInfo (RFC 8017 mục 9.2) của từng hàm băm
+var rsaDigestInfoPrefixes = map[crypto.Hash][]byte{
+	crypto.SHA256: {0x30, 0x31, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x01, 0x05, 0x00, 0x04, 0x20},
+	crypto.SHA384: {0x30, 0x41, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x02, 0x05, 0x00, 0x04, 0x30},
+	crypto.SHA512: {0x30, 0x51, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x03, 0x05, 0x00, 0x04, 0x40},
+}
+
+// Đọc lược đồ từ yêu cầu ký: scheme "pkcs1v15" (mặc định) hoặc "pss",
+// hash "SHA-256" (mặc định), "SHA-384" hoặc "SHA-512", saltLength "auto" (mặc định) hoặc "equals-hash" cho PSS
+func parseRSASignatureScheme(scheme, hash, saltLength string) (rsaSignatureScheme, error) {
+	var s rsaSignatureScheme
+
+	switch strings.ToLower(scheme) {
+	case "", "pkcs1v15":
+	case "pss":
+		s.PSS = true
+	default:
+		return s, fmt.Errorf("lược đồ chữ ký RSA không được hỗ trợ: %s", scheme)
+	}
+
+	if hash == "" {
+		hash = "SHA-256"
+	}
+	h, ok := rsaSignatureHashes[strings.ReplaceAll(strings.ToLower(hash), "-", "")]
+	if !ok {
+		return s, fmt.Errorf("hàm băm không được hỗ trợ: %s", hash)
+	}
+	s.Hash = h
+
+	switch strings.ToLower(saltLength) {
+	case "":
+		if s.PSS {
+			s.SaltLength = rsa.PSSSaltLengthAuto
+		}
+	case "auto", "equals-hash":
+		if !s.PSS {
+			return s, errors.New("saltLength chỉ dùng cho lược đồ PSS")
+		}
+		s.SaltLength = rsa.PSSSaltLengthAuto
+		if strings.ToLower(saltLength) == "equals-hash" {
+			s.SaltLength = rsa.PSSSaltLengthEqualsHash
+		}
+	default:
+		return s, fmt.Errorf("saltLength không được hỗ trợ: %s", saltLength)
+	}
+	return s, nil
+}
+
+// Tên lược đồ ghi ở đầu chữ ký, ví dụ "pss-sha384"
+func (s rsaSignatureScheme) name() string {
+	name := "pkcs1v15"
+	if s.PSS {
+		name = "pss"
+	}
+	for n, h := range rsaSignatureHashes {
+		if h == s.Hash {
+			return name + "-" + n
+		}
+	}
+	return name
+}
+
+// Giá trị băm của thông điệp theo lược đồ
+func (s rsaSignatureScheme) digest(message string) []byte {
+	h := s.Hash
+	if h == 0 {
+		h = crypto.SHA256
+	}
+	hasher := h.New()
+	hasher.Write([]byte(message))
+	return hasher.Sum(nil)
+}
+
+// Tách lược đồ khỏi chữ ký "<lược đồ>:<base64>".
+// Chữ ký không có tiền tố là chữ ký cũ (Hash = 0).
+func splitRSASignature(signature string) (rsaSignatureScheme, string, error) {
+	prefix, rest, found := strings.Cut(signature, ":")
+	if !found {
+		return rsaSignatureScheme{}, signature, nil
+	}
+
+	name, hash, _ := strings.Cut(prefix, "-")
+	h, ok := rsaSignatureHashes[hash]
+	if !ok {
+		return rsaSignatureScheme{}, "", fmt.Errorf("lược đồ chữ ký RSA không được hỗ trợ: %s", prefix)
+	}
+	switch name {
+	case "pkcs1v15":
+		return rsaSignatureScheme{Hash: h}, rest, nil
+	case "pss":
+		// Độ dài salt được phát hiện khi xác thực
+		return rsaSignatureScheme{PSS: true, Hash: h, SaltLength: rsa.PSSSaltLengthAuto}, rest, nil
+	}
+	return rsaSignatureScheme{}, "", fmt.Errorf("lược đồ chữ ký RSA không được hỗ trợ: %s", prefix)
+}
@@ -0,0 +1,339 @@
+// rsatrace.go
+package main
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"math/big"
+)
+
+// RSA tự cài đặt cho chế độ trace: cùng định dạng và kết quả với rsa.EncryptOAEP / rsa.SignPKCS1v15 / rsa.SignPSS
+// (OAEP SHA-256 không nhãn, chữ ký theo rsaSignatureScheme) nhưng ghi lại các bước đệm.
+// Phép toán với khóa bí mật luôn được làm mù và không ghi vào trace.
+
+// MGF1 với hàm băm hash
+func mgf1(hash crypto.Hash, seed []byte, length int) []byte {
+	var out []byte
+	counter := make([]byte, 4)
+	for i := uint32(0); len(out) < length; i++ {
+		binary.BigEndian.PutUint32(counter, i)
+		h := hash.New()
+		h.Write(seed)
+		h.Write(counter)
+		out = h.Sum(out)
+	}
+	return out[:length]
+}
+
+// Tính c^d mod n với làm mù (blinding) và CRT như crypto/rsa:
+// c' = c*r^e mod n, m' = c'^d mod n (tính theo p và q), m = m'*r^-1 mod n.
+// Kết quả được kiểm tra lại bằng khóa công khai.
+func rsaPrivateOp(priv *rsa.PrivateKey, c *big.Int) (*big.Int, error) {
+	n := priv.N
+	e := big.NewInt(int64(priv.E))
+
+	var r, rInv *big.Int
+	for rInv == nil {
+		var err error
+		if r, err = rand.Int(rand.Reader, n); err != nil {
+			return nil, err
+		}
+		if r.Sign() > 0 {
+			rInv = new(big.Int).ModInverse(r, n)
+		}
+	}
+	blinded := new(big.Int).Exp(r, e, n)
+	blinded.Mul(blinded, c).Mod(blinded, n)
+
+	var m *big.Int
+	if pre := priv.Precomputed; len(priv.Primes) == 2 && pre.Dp != nil && pre.Dq != nil && pre.Qinv != nil {
+		p, q := priv.Primes[0], priv.Primes[1]
+		mp := new(big.Int).Exp(blinded, pre.Dp, p)
+		mq := new(big.Int).Exp(blinded, pre.Dq, q)
+		m = mp.Sub(mp, mq)
+		m.Mul(m, pre.Qinv).Mod(m, p)
+		m.Mul(m, q).Add(m, mq)
+	} else {
+		m = new(big.Int).Exp(blinded, priv.D, n)
+	}
+	m.Mul(m, rInv).Mod(m, n)
+
+	if new(big.Int).Exp(m, e, n).Cmp(c) != 0 {
+		return nil, errors.New("rsa: lỗi khi tính với khóa bí mật")
+	}
+	return m, nil
+}
+
+func xorBytes(a, b []byte) []byte {
+	out := make([]byte, len(a))
+	for i := range a {
+		out[i] = a[i] ^ b[i]
+	}
+	return out
+}
+
+// Mã hóa RSA-OAEP từng khối như encryptRSA, ghi lại các giá trị trung gian
+func encryptRSATrace(pub *rsa.PublicKey, message string, tr *Trace) (string, error) {
+	k := pub.Size()
+	hLen := sha256.Size
+	lHash := sha256.Sum256(nil)
+	e := big.NewInt(int64(pub.E))
+
+	var encryptedBlocks []string
+	for i, block := range splitRSAMessage([]byte(message), k-2*hLen-2) {
+		tr.setBlock(i + 1)
+		tr.add("M", block)
+
+		// DB = lHash || PS || 0x01 || M
+		db := make([]byte, k-hLen-1)
+		copy(db, lHash[:])
+		db[len(db)-len(block)-1] = 0x01
+		copy(db[len(db)-len(block):], block)
+		tr.add("DB = lHash || PS || 01 || M", db)
+
+		seed := make([]byte, hLen)
+		if _, err := rand.Read(seed); err != nil {
+			return "", err
+		}
+		tr.add("seed", seed)
+		maskedDB := xorBytes(db, mgf1(crypto.SHA256, seed, len(db)))
+		tr.add("maskedDB = DB xor MGF1(seed)", maskedDB)
+		maskedSeed := xorBytes(seed, mgf1(crypto.SHA256, maskedDB, hLen))
+		tr.add("maskedSeed = seed xor MGF1(maskedDB)", maskedSeed)
+
+		em := append(append([]byte{0x00}, maskedSeed...), maskedDB...)
+		tr.add("EM = 00 || maskedSeed || maskedDB", em)
+
+		m := new(big.Int).SetBytes(em)
+		c := new(big.Int).Exp(m, e, pub.N)
+		tr.add("m = OS2IP(EM)", m)
+		tr.add("c = m^e mod n", c)
+
+		encryptedBlocks = append(encryptedBlocks, base64.StdEncoding.EncodeToString(c.FillBytes(make([]byte, k))))
+	}
+
+	encryptedMessage, err := json.Marshal(encryptedBlocks)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(encryptedMessage), nil
+}
+
+// Giải mã RSA-OAEP từng khối như decryptRSA, ghi lại các giá trị trung gian
+func decryptRSATrace(priv *rsa.PrivateKey, encryptedMessage string, tr *Trace) (string, error) {
+	decodedMessage, _ := base64.StdEncoding.DecodeString(encryptedMessage)
+
+	var encryptedBlocks []string
+	if err := json.Unmarshal(decodedMessage, &encryptedBlocks); err != nil {
+		return "", err
+	}
+
+	k := priv.Size()
+	hLen := sha256.Size
+	lHash := sha256.Sum256(nil)
+
+	var decryptedMessage []byte
+	for i, block := range encryptedBlocks {
+		tr.setBlock(i + 1)
+		ciphertext, _ := base64.StdEncoding.DecodeString(block)
+		c := new(big.Int).SetBytes(ciphertext)
+		if len(ciphertext) != k || c.Cmp(priv.N) >= 0 {
+			return "", rsa.ErrDecryption
+		}
+		tr.add("c", c)
+
+		m, err := rsaPrivateOp(priv, c)
+		if err != nil {
+			return "", err
+		}
+		em := m.FillBytes(make([]byte, k))
+		tr.add("EM = I2OSP(m)", em)
+
+		maskedSeed, maskedDB := em[1:1+hLen], em[1+hLen:]
+		seed := xorBytes(maskedSeed, mgf1(crypto.SHA256, maskedDB, hLen))
+		tr.add("seed = maskedSeed xor MGF1(maskedDB)", seed)
+		db := xorBytes(maskedDB, mgf1(crypto.SHA256, seed, len(maskedDB)))
+		tr.add("DB = maskedDB xor MGF1(seed)", db)
+
+		// Kiểm tra byte đầu, lHash và dấu phân cách 0x01 sau PS
+		rest := bytes.TrimLeft(db[hLen:], "\x00")
+		if em[0] != 0 || !bytes.Equal(db[:hLen], lHash[:]) || len(rest) == 0 || rest[0] != 0x01 {
+			return "", rsa.ErrDecryption
+		}
+		tr.add("M", rest[1:])
+		decryptedMessage = append(decryptedMessage, rest[1:]...)
+	}
+
+	return string(decryptedMessage), nil
+}
+
+// Khối PKCS#1 v1.5 của chữ ký: EM = 00 || 01 || FF..FF || 00 || T với T = DigestInfo || H(m).
+// Chữ ký cũ (Hash = 0) ký trực tiếp giá trị băm SHA-256 nên T = H(m).
+func pkcs1v15SignatureBlock(k int, message string, scheme rsaSignatureScheme, tr *Trace) ([]byte, error) {
+	hashed := scheme.digest(message)
+	tr.add("H = Hash(m)", hashed)
+
+	t := append(append([]byte{}, rsaDigestInfoPrefixes[scheme.Hash]...), hashed...)
+	if k < len(t)+11 {
+		return nil, rsa.ErrMessageTooLong
+	}
+	if scheme.Hash != 0 {
+		tr.add("T = DigestInfo || H", t)
+	}
+	em := make([]byte, k)
+	em[1] = 0x01
+	for i := 2; i < k-len(t)-1; i++ {
+		em[i] = 0xff
+	}
+	copy(em[k-len(t):], t)
+	tr.add("EM = 00 || 01 || FF..FF || 00 || T", em)
+	return em, nil
+}
+
+// Mã hóa EMSA-PSS (RFC 8017 mục 9.1.1): EM = maskedDB || H' || BC
+func pssEncode(emBits int, message string, scheme rsaSignatureScheme, tr *Trace) ([]byte, error) {
+	hLen := scheme.Hash.Size()
+	emLen := (emBits + 7) / 8
+	mHash := scheme.digest(message)
+	tr.add("H = Hash(m)", mHash)
+
+	sLen := scheme.SaltLength
+	if sLen == rsa.PSSSaltLengthEqualsHash {
+		sLen = hLen
+	} else if sLen == rsa.PSSSaltLengthAuto {
+		sLen = emLen - 2 - hLen
+	}
+	if emLen < hLen+sLen+2 {
+		return nil, rsa.ErrMessageTooLong
+	}
+	salt := make([]byte, sLen)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	tr.add("salt", salt)
+
+	hPrime := pssHash(scheme.Hash, mHash, salt)
+	tr.add("H' = Hash(00*8 || H || salt)", hPrime)
+
+	// DB = PS || 0x01 || salt
+	db := make([]byte, emLen-hLen-1)
+	db[len(db)-sLen-1] = 0x01
+	copy(db[len(db)-sLen:], salt)
+	tr.add("DB = PS || 01 || salt", db)
+
+	maskedDB := xorBytes(db, mgf1(scheme.Hash, hPrime, len(db)))
+	maskedDB[0] &= 0xff >> (8*emLen - emBits)
+	tr.add("maskedDB = DB xor MGF1(H')", maskedDB)
+
+	em := append(append(maskedDB, hPrime...), 0xbc)
+	tr.add("EM = maskedDB || H' || BC", em)
+	return em, nil
+}
+
+// Kiểm tra EMSA-PSS, độ dài salt được phát hiện từ DB
+func pssVerify(em []byte, emBits int, message string, scheme rsaSignatureScheme, tr *Trace) bool {
+	hLen := scheme.Hash.Size()
+	emLen := (emBits + 7) / 8
+	mHash := scheme.digest(message)
+	tr.add("H = Hash(m)", mHash)
+
+	if len(em) != emLen || emLen < hLen+2 || em[emLen-1] != 0xbc {
+		return false
+	}
+	maskedDB, hPrime := em[:emLen-hLen-1], em[emLen-hLen-1:emLen-1]
+	if maskedDB[0]&^(0xff>>(8*emLen-emBits)) != 0 {
+		return false
+	}
+
+	db := xorBytes(maskedDB, mgf1(scheme.Hash, hPrime, len(maskedDB)))
+	db[0] &= 0xff >> (8*emLen - emBits)
+	tr.add("DB = maskedDB xor MGF1(H')", db)
+
+	rest := bytes.TrimLeft(db, "\x00")
+	if len(rest) == 0 || rest[0] != 0x01 {
+		return false
+	}
+	salt := rest[1:]
+	if scheme.SaltLength > 0 && len(salt) != scheme.SaltLength {
+		return false
+	}
+	tr.add("salt", salt)
+
+	expected := pssHash(scheme.Hash, mHash, salt)
+	tr.add("Hash(00*8 || H || salt)", expected)
+	return bytes.Equal(hPrime, expected)
+}
+
+// H' = Hash(00 00 00 00 00 00 00 00 || mHash || salt)
+func pssHash(hash crypto.Hash, mHash, salt []byte) []byte {
+	h := hash.New()
+	h.Write(make([]byte, 8))
+	h.Write(mHash)
+	h.Write(salt)
+	return h.Sum(nil)
+}
+
+// Ký như signMessage, ghi lại khối đã đệm và chữ ký s = m^d mod n
+func signRSATrace(priv *rsa.PrivateKey, message string, scheme rsaSignatureScheme, tr *Trace) ([]byte, error) {
+	k := priv.Size()
+	tr.add("scheme", scheme.name())
+
+	var em []byte
+	var err error
+	if scheme.PSS {
+		em, err = pssEncode(priv.N.BitLen()-1, message, scheme, tr)
+	} else {
+		em, err = pkcs1v15SignatureBlock(k, message, scheme, tr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	m := new(big.Int).SetBytes(em)
+	tr.add("m = OS2IP(EM)", m)
+	s, err := rsaPrivateOp(priv, m)
+	if err != nil {
+		return nil, err
+	}
+	tr.add("s = m^d mod n", s)
+	return s.FillBytes(make([]byte, k)), nil
+}
+
+// Xác thực như verifySignature, ghi lại m = s^e mod n và khối mong đợi
+func verifyRSATrace(pub *rsa.PublicKey, message string, signature []byte, scheme rsaSignatureScheme, tr *Trace) bool {
+	k := pub.Size()
+	s := new(big.Int).SetBytes(signature)
+	if len(signature) != k || s.Cmp(pub.N) >= 0 {
+		return false
+	}
+	tr.add("scheme", scheme.name())
+	tr.add("s", s)
+
+	m := new(big.Int).Exp(s, big.NewInt(int64(pub.E)), pub.N)
+	tr.add("m = s^e mod n", m)
+
+	if scheme.PSS {
+		emBits := pub.N.BitLen() - 1
+		if m.BitLen() > emBits {
+			return false
+		}
+		em := m.FillBytes(make([]byte, (emBits+7)/8))
+		tr.add("EM = I2OSP(m)", em)
+		return pssVerify(em, emBits, message, scheme, tr)
+	}
+
+	em := m.FillBytes(make([]byte, k))
+	tr.add("EM' = I2OSP(m)", em)
+	expected, err := pkcs1v15SignatureBlock(k, message, scheme, tr)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(em, expected)
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"strings"
+	"testing"
+)
+
+func testRSAKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return priv
+}
+
+// Thông điệp dài hơn một khối OAEP để kiểm tra cả việc chia khối
+var rsaTraceMessage = strings.Repeat("Xin chào RSA trace! ", 20)
+
+func TestRSATraceEncryptionMatchesCryptoRSA(t *testing.T) {
+	priv := testRSAKey(t)
+
+	// Mã hóa có trace, giải mã bằng crypto/rsa
+	tr := newTrace(true)
+	ciphertext, err := encryptRSA(&priv.PublicKey, rsaTraceMessage, tr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tr.Steps()) == 0 {
+		t.Fatal("trace rỗng")
+	}
+	plaintext, err := decryptRSA(priv, ciphertext, nil)
+	if err != nil || plaintext != rsaTraceMessage {
+		t.Fatalf("crypto/rsa giải mã bản mã trace: %q, %v", plaintext, err)
+	}
+
+	// Mã hóa bằng crypto/rsa, giải mã có trace
+	ciphertext, err = encryptRSA(&priv.PublicKey, rsaTraceMessage, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr = newTrace(true)
+	plaintext, err = decryptRSA(priv, ciphertext, tr)
+	if err != nil || plaintext != rsaTraceMessage {
+		t.Fatalf("giải mã trace bản mã crypto/rsa: %q, %v", plaintext, err)
+	}
+	for _, step := range tr.Steps() {
+		if strings.Contains(step.Value, priv.D.Text(16)) {
+			t.Fatalf("trace chứa khóa bí mật ở bước %q", step.Name)
+		}
+	}
+}
+
+func TestRSATraceDecryptRejectsTamperedCiphertext(t *testing.T) {
+	priv := testRSAKey(t)
+	ciphertext, err := encryptRSA(&priv.PublicKey, "hello", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := testRSAKey(t)
+	if _, err := decryptRSA(other, ciphertext, newTrace(true)); err == nil {
+		t.Fatal("giải mã được bằng khóa khác")
+	}
+}
+
+func TestRSATraceSignatureMatchesCryptoRSA(t *testing.T) {
+	priv := testRSAKey(t)
+
+	for _, c := range []struct{ scheme, hash, saltLength string }{
+		{"pkcs1v15", "SHA-256", ""},
+		{"pkcs1v15", "SHA-512", ""},
+		{"pss", "SHA-256", ""},
+		{"pss", "SHA-384", "equals-hash"},
+	} {
+		scheme, err := parseRSASignatureScheme(c.scheme, c.hash, c.saltLength)
+		if err != nil {
+			t.Fatal(err)
+		}
+		name := scheme.name()
+
+		// Ký có trace, xác thực bằng crypto/rsa
+		signature, err := signMessage(priv, rsaTraceMessage, scheme, newTrace(true))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !verifySignature(&priv.PublicKey, rsaTraceMessage, signature, nil) {
+			t.Errorf("%s: crypto/rsa không xác thực được chữ ký trace", name)
+		}
+
+		// Ký bằng crypto/rsa, xác thực có trace
+		signature, err = signMessage(priv, rsaTraceMessage, scheme, nil)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !verifySignature(&priv.PublicKey, rsaTraceMessage, signature, newTrace(true)) {
+			t.Errorf("%s: trace không xác thực được chữ ký crypto/rsa", name)
+		}
+		if verifySignature(&priv.PublicKey, rsaTraceMessage+".", signature, newTrace(true)) {
+			t.Errorf("%s: trace chấp nhận chữ ký cho thông điệp khác", name)
+		}
+	}
+}
+
+func TestRSAPrivateOpWithoutPrecomputedValues(t *testing.T) {
+	priv := testRSAKey(t)
+	bare := &rsa.PrivateKey{PublicKey: priv.PublicKey, D: priv.D, Primes: priv.Primes}
+
+	m, err := randScalar(priv.N)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s1, err := rsaPrivateOp(priv, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s2, err := rsaPrivateOp(bare, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s1.Cmp(s2) != 0 {
+		t.Fatal("CRT và lũy thừa trực tiếp cho kết quả khác nhau")
+	}
+}
@@ -0,0 +1,226 @@
+// schnorr.go
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// Chữ ký Schnorr BIP-340 trên secp256k1, tự cài đặt bằng các phép toán điểm trong ecc.go
+// để so sánh trực tiếp với ECDSA (signECDSATextbook) trên cùng đường cong.
+// Khóa công khai x-only 32 byte, chữ ký 64 byte R.x || s dạng hex.
+// pointMultiply không chạy trong thời gian hằng nên Schnorr ký bằng khóa SCHNORR riêng,
+// không dùng khóa SECP256K1 của chữ ký Ethereum.
+
+// Số chữ ký tối đa trong một lần xác thực theo lô
+const schnorrMaxBatch = 256
+
+// Một chữ ký trong lô; publicKey là khóa x-only dạng hex
+type SchnorrBatchEntry struct {
+	Message   string `json:"message"`
+	Signature string `json:"signature"`
+	PublicKey string `json:"publicKey,omitempty"`
+}
+
+func schnorrCurve() *Curve {
+	return ecCurves["secp256k1"]
+}
+
+// Số nguyên dạng 32 byte big-endian
+func bytes32(x *big.Int) []byte {
+	return x.FillBytes(make([]byte, 32))
+}
+
+// Băm có nhãn: SHA256(SHA256(tag) || SHA256(tag) || x)
+func taggedHash(tag string, data ...[]byte) []byte {
+	tagHash := sha256.Sum256([]byte(tag))
+	h := sha256.New()
+	h.Write(tagHash[:])
+	h.Write(tagHash[:])
+	for _, d := range data {
+		h.Write(d)
+	}
+	return h.Sum(nil)
+}
+
+// Điểm có hoành độ x và tung độ chẵn, nil nếu không tồn tại
+func liftX(c *Curve, x *big.Int) (*big.Int, *big.Int) {
+	if x.Cmp(c.P) >= 0 {
+		return nil, nil
+	}
+	y := new(big.Int).ModSqrt(c.rhs(x), c.P)
+	if y == nil {
+		return nil, nil
+	}
+	if y.Bit(0) == 1 {
+		y.Sub(c.P, y)
+	}
+	return x, y
+}
+
+// e = hash_challenge(R.x || P.x || m) mod n
+func schnorrChallenge(c *Curve, rx, px []byte, message []byte) *big.Int {
+	e := new(big.Int).SetBytes(taggedHash("BIP0340/challenge", rx, px, message))
+	return e.Mod(e, c.N)
+}
+
+// Khóa công khai x-only của khóa secp256k1
+func schnorrPublicKey(privateKey *ecdsa.PrivateKey) []byte {
+	return bytes32(privateKey.X)
+}
+
+// Hàm ký Schnorr với dữ liệu ngẫu nhiên phụ aux 32 byte
+func signSchnorrAux(d0 *big.Int, message, aux []byte, tr *Trace) ([]byte, error) {
+	c := schnorrCurve()
+	if d0.Sign() <= 0 || d0.Cmp(c.N) >= 0 {
+		return nil, errors.New("khóa bí mật phải nằm trong [1, n-1]")
+	}
+
+	// P = d'*G, đổi d = n - d' nếu P có tung độ lẻ để P luôn có tung độ chẵn.
+	// Trace chỉ ghi các điểm P, R, không ghi d, t, k và các bước nhân điểm với chúng.
+	d := new(big.Int).Set(d0)
+	px, py := pointMultiply(c, d, c.Gx, c.Gy)
+	if py.Bit(0) == 1 {
+		d.Sub(c.N, d)
+	}
+	tr.add("P.x", bytes32(px))
+
+	// Nonce xác định từ khóa, thông điệp và aux
+	t := xorBytes(bytes32(d), taggedHash("BIP0340/aux", aux))
+	k := new(big.Int).SetBytes(taggedHash("BIP0340/nonce", t, bytes32(px), message))
+	k.Mod(k, c.N)
+	if k.Sign() == 0 {
+		return nil, errors.New("nonce bằng 0, hãy ký lại")
+	}
+
+	rx, ry := pointMultiply(c, k, c.Gx, c.Gy)
+	if ry.Bit(0) == 1 {
+		k.Sub(c.N, k)
+	}
+	tr.add("R.x", bytes32(rx))
+
+	e := schnorrChallenge(c, bytes32(rx), bytes32(px), message)
+	tr.add("e = hash_challenge(R.x || P.x || m) mod n", e)
+	s := new(big.Int).Mul(e, d)
+	s.Add(s, k).Mod(s, c.N)
+	tr.add("s = k + e*d mod n", s)
+
+	return append(bytes32(rx), bytes32(s)...), nil
+}
+
+// Hàm ký thông điệp bằng Schnorr BIP-340
+func signSchnorr(privateKey *ecdsa.PrivateKey, message string, tr *Trace) (string, error) {
+	aux := make([]byte, 32)
+	if _, err := rand.Read(aux); err != nil {
+		return "", err
+	}
+	sig, err := signSchnorrAux(privateKey.D, []byte(message), aux, tr)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(sig), nil
+}
+
+// Đọc chữ ký 64 byte thành r, s; lỗi nếu r >= p hoặc s >= n
+func decodeSchnorrSignature(c *Curve, signature string) (*big.Int, *big.Int, error) {
+	sig, err := hex.DecodeString(signature)
+	if err != nil || len(sig) != 64 {
+		return nil, nil, errors.New("chữ ký Schnorr phải gồm 64 byte dạng hex")
+	}
+	r, s := new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])
+	if r.Cmp(c.P) >= 0 || s.Cmp(c.N) >= 0 {
+		return nil, nil, errors.New("chữ ký Schnorr không hợp lệ")
+	}
+	return r, s, nil
+}
+
+// Hàm xác thực chữ ký Schnorr: R = s*G - e*P phải có tung độ chẵn và R.x = r
+func verifySchnorr(publicKey []byte, message, signature string, tr *Trace) (bool, error) {
+	c := schnorrCurve()
+	if len(publicKey) != 32 {
+		return false, errors.New("khóa công khai x-only phải gồm 32 byte")
+	}
+	px, py := liftX(c, new(big.Int).SetBytes(publicKey))
+	if px == nil {
+		return false, errors.New("khóa công khai không nằm trên đường cong")
+	}
+	r, s, err := decodeSchnorrSignature(c, signature)
+	if err != nil {
+		return false, err
+	}
+	tr.addPoint("P = lift_x(pk)", px, py)
+
+	e := schnorrChallenge(c, bytes32(r), publicKey, []byte(message))
+	tr.add("e = hash_challenge(r || P.x || m) mod n", e)
+
+	sgx, sgy := tr.pointMultiply("s*G", c, s, c.Gx, c.Gy)
+	epx, epy := tr.pointMultiply("e*P", c, e, px, py)
+	epx, epy = pointNeg(c, epx, epy)
+	rx, ry := pointAdd(c, sgx, sgy, epx, epy)
+	tr.addPoint("R = s*G - e*P", rx, ry)
+
+	return rx != nil && ry.Bit(0) == 0 && rx.Cmp(r) == 0, nil
+}
+
+// Xác thực theo lô (BIP-340): với a_1 = 1 và a_2..a_u ngẫu nhiên, kiểm tra
+// (s_1 + a_2*s_2 + ... + a_u*s_u)*G = R_1 + a_2*R_2 + ... + e_1*P_1 + (a_2*e_2)*P_2 + ...
+// Lô chỉ hợp lệ khi mọi chữ ký đều hợp lệ (trừ xác suất không đáng kể).
+func verifySchnorrBatch(publicKeys [][]byte, entries []SchnorrBatchEntry, tr *Trace) (bool, error) {
+	c := schnorrCurve()
+	if len(entries) == 0 || len(entries) > schnorrMaxBatch {
+		return false, fmt.Errorf("lô phải có từ 1 đến %d chữ ký", schnorrMaxBatch)
+	}
+
+	sum := new(big.Int)
+	var rhsX, rhsY *big.Int
+	for i, entry := range entries {
+		tr.setBlock(i + 1)
+		if len(publicKeys[i]) != 32 {
+			return false, nil
+		}
+		px, py := liftX(c, new(big.Int).SetBytes(publicKeys[i]))
+		if px == nil {
+			return false, nil
+		}
+		r, s, err := decodeSchnorrSignature(c, entry.Signature)
+		if err != nil {
+			return false, nil
+		}
+		rx, ry := liftX(c, r)
+		if rx == nil {
+			return false, nil
+		}
+		e := schnorrChallenge(c, bytes32(r), publicKeys[i], []byte(entry.Message))
+		tr.add("e", e)
+
+		a := big.NewInt(1)
+		if i > 0 {
+			if a, err = randScalar(c.N); err != nil {
+				return false, err
+			}
+		}
+		tr.add("a", a)
+
+		// Vế trái: cộng dồn a_i*s_i; vế phải: cộng dồn a_i*R_i + (a_i*e_i)*P_i
+		sum.Add(sum, new(big.Int).Mul(a, s)).Mod(sum, c.N)
+		arx, ary := pointMultiply(c, a, rx, ry)
+		rhsX, rhsY = pointAdd(c, rhsX, rhsY, arx, ary)
+		aepx, aepy := pointMultiply(c, new(big.Int).Mod(new(big.Int).Mul(a, e), c.N), px, py)
+		rhsX, rhsY = pointAdd(c, rhsX, rhsY, aepx, aepy)
+	}
+	tr.setBlock(0)
+
+	tr.add("Σ a_i*s_i mod n", sum)
+	lhsX, lhsY := tr.pointMultiply("(Σ a_i*s_i)*G", c, sum, c.Gx, c.Gy)
+	tr.addPoint("Σ a_i*R_i + (a_i*e_i)*P_i", rhsX, rhsY)
+
+	if lhsX == nil || rhsX == nil {
+		return lhsX == nil && rhsX == nil, nil
+	}
+	return lhsX.Cmp(rhsX) == 0 && lhsY.Cmp(rhsY) == 0, nil
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"encoding/hex"
+	"math/big"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// Các vector kiểm thử tham chiếu của BIP-340 (bip-0340/test-vectors.csv, chỉ số 0-14)
+var bip340Vectors = []struct {
+	index     int
+	secretKey string
+	publicKey string
+	auxRand   string
+	message   string
+	signature string
+	valid     bool
+}{
+	{0, "0000000000000000000000000000000000000000000000000000000000000003", "F9308A019258C31049344F85F89D5229B531C845836F99B08601F113BCE036F9",
+		"0000000000000000000000000000000000000000000000000000000000000000", "0000000000000000000000000000000000000000000000000000000000000000",
+		"E907831F80848D1069A5371B402410364BDF1C5F8307B0084C55F1CE2DCA821525F66A4A85EA8B71E482A74F382D2CE5EBEEE8FDB2172F477DF4900D310536C0", true},
+	{1, "B7E151628AED2A6ABF7158809CF4F3C762E7160F38B4DA56A784D9045190CFEF", "DFF1D77F2A671C5F36183726DB2341BE58FEAE1DA2DECED843240F7B502BA659",
+		"0000000000000000000000000000000000000000000000000000000000000001", "243F6A8885A308D313198A2E03707344A4093822299F31D0082EFA98EC4E6C89",
+		"6896BD60EEAE296DB48A229FF71DFE071BDE413E6D43F917DC8DCF8C78DE33418906D11AC976ABCCB20B091292BFF4EA897EFCB639EA871CFA95F6DE339E4B0A", true},
+	{2, "C90FDAA22168C234C4C6628B80DC1CD129024E088A67CC74020BBEA63B14E5C9", "DD308AFEC5777E13121FA72B9CC1B7CC0139715309B086C960E18FD969774EB8",
+		"C87AA53824B4D7AE2EB035A2B5BBBCCC080E76CDC6D1692C4B0B62D798E6D906", "7E2D58D8B3BCDF1ABADEC7829054F90DDA9805AAB56C77333024B9D0A508B75C",
+		"5831AAEED7B44BB74E5EAB94BA9D4294C49BCF2A60728D8B4C200F50DD313C1BAB745879A5AD954A72C45A91C3A51D3C7ADEA98D82F8481E0E1E03674A6F3FB7", true},
+	{3, "0B432B2677937381AEF05BB02A66ECD012773062CF3FA2549E44F58ED2401710", "25D1DFF95105F5253C4022F628A996AD3A0D95FBF21D468A1B33F8C160D8F517",
+		"FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFF", "FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFF",
+		"7EB0509757E246F19449885651611CB965ECC1A187DD51B64FDA1EDC9637D5EC97582B9CB13DB3933705B32BA982AF5AF25FD78881EBB32771FC5922EFC66EA3", true},
+	{4, "", "D69C3509BB99E412E68B0FE8544E72837DFA30746D8BE2AA65975F29D22DC7B9", "",
+		"4DF3C3F68FCC83B27E9D42C90431A72499F17875C81A599B566C9889B9696703",
+		"00000000000000000000003B78CE563F89A0ED9414F5AA28AD0D96D6795F9C6376AFB1548AF603B3EB45C9F8207DEE1060CB71C04E80F593060B07D28308D7F4", true},
+	// Khóa công khai không nằm trên đường cong
+	{5, "", "EEFDEA4CDB677750A420FEE807EACF21EB9898AE79B9768766E4FAA04A2D4A34", "",
+		"243F6A8885A308D313198A2E03707344A4093822299F31D0082EFA98EC4E6C89",
+		"6CFF5C3BA86C69EA4B7376F31A9BCB4F74C1976089B2D9963DA2E5543E17776969E89B4C5564D00349106B8497785DD7D1D713A8AE82B32FA79D5F7FC407D39B", false},
+	// R có tung độ lẻ
+	{6, "", "DFF1D77F2A671C5F36183726DB2341BE58FEAE1DA2DECED843240F7B502BA659", "",
+		"243F6A8885A308D313198A2E03707344A4093822299F31D0082EFA98EC4E6C89",
+		"FFF97BD5755EEEA420453A14355235D382F6472F8568A18B2F057A14602975563CC27944640AC607CD107AE10923D9EF7A73C643E166BE5EBEAFA34B1AC553E2", false},
+	// s bị đổi dấu
+	{7, "", "DFF1D77F2A671C5F36183726DB2341BE58FEAE1DA2DECED843240F7B502BA659", "",
+		"243F6A8885A308D313198A2E03707344A4093822299F31D0082EFA98EC4E6C89",
+		"1FA62E331EDBC21C394792D2AB1100A7B432B013DF3F6FF4F99FCB33E0E1515F28890B3EDB6E7189B630448B515CE4F8622A954CFE545735AAEA5134FCCDB2BD", false},
+	// sG - eP là điểm vô cực (hai trường hợp)
+	{8, "", "DFF1D77F2A671C5F36183726DB2341BE58FEAE1DA2DECED843240F7B502BA659", "",
+		"243F6A8885A308D313198A2E03707344A4093822299F31D0082EFA98EC4E6C89",
+		"6CFF5C3BA86C69EA4B7376F31A9BCB4F74C1976089B2D9963DA2E5543E177769961764B3AA9B2FFCB6EF947B6887A226E8D7C93E00C5ED0C1834FF0D0C2E6DA6", false},
+	{9, "", "DFF1D77F2A671C5F36183726DB2341BE58FEAE1DA2DECED843240F7B502BA659", "",
+		"243F6A8885A308D313198A2E03707344A4093822299F31D0082EFA98EC4E6C89",
+		"0000000000000000000000000000000000000000000000000000000000000000123DDA8328AF9C23A94C1FEECFD123BA4FB73476F0D594DCB65C6425BD186051", false},
+	{10, "", "DFF1D77F2A671C5F36183726DB2341BE58FEAE1DA2DECED843240F7B502BA659", "",
+		"243F6A8885A308D313198A2E03707344A4093822299F31D0082EFA98EC4E6C89",
+		"00000000000000000000000000000000000000000000000000000000000000017615FBAF5AE28864013C099742DEADB4DBA87F11AC6754F93780D5A1837CF197", false},
+	// r không phải hoành độ của điểm nào
+	{11, "", "DFF1D77F2A671C5F36183726DB2341BE58FEAE1DA2DECED843240F7B502BA659", "",
+		"243F6A8885A308D313198A2E03707344A4093822299F31D0082EFA98EC4E6C89",
+		"4A298DACAE57395A15D0795DDBFD1DCB564DA82B0F269BC70A74F8220429BA1D69E89B4C5564D00349106B8497785DD7D1D713A8AE82B32FA79D5F7FC407D39B", false},
+	// r = p
+	{12, "", "DFF1D77F2A671C5F36183726DB2341BE58FEAE1DA2DECED843240F7B502BA659", "",
+		"243F6A8885A308D313198A2E03707344A4093822299F31D0082EFA98EC4E6C89",
+		"FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFEFFFFFC2F69E89B4C5564D00349106B8497785DD7D1D713A8AE82B32FA79D5F7FC407D39B", false},
+	// s = n
+	{13, "", "DFF1D77F2A671C5F36183726DB2341BE58FEAE1DA2DECED843240F7B502BA659", "",
+		"243F6A8885A308D313198A2E03707344A4093822299F31D0082EFA98EC4E6C89",
+		"6CFF5C3BA86C69EA4B7376F31A9BCB4F74C1976089B2D9963DA2E5543E177769FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFEBAAEDCE6AF48A03BBFD25E8CD0364141", false},
+	// Khóa công khai vượt quá p
+	{14, "", "FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFEFFFFFC30", "",
+		"243F6A8885A308D313198A2E03707344A4093822299F31D0082EFA98EC4E6C89",
+		"6CFF5C3BA86C69EA4B7376F31A9BCB4F74C1976089B2D9963DA2E5543E17776969E89B4C5564D00349106B8497785DD7D1D713A8AE82B32FA79D5F7FC407D39B", false},
+}
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestSchnorrBIP340Vectors(t *testing.T) {
+	c := schnorrCurve()
+	for _, v := range bip340Vectors {
+		publicKey := mustHex(t, v.publicKey)
+		message := mustHex(t, v.message)
+
+		if v.secretKey != "" {
+			d := new(big.Int).SetBytes(mustHex(t, v.secretKey))
+			px, _ := pointMultiply(c, d, c.Gx, c.Gy)
+			if got := hex.EncodeToString(bytes32(px)); !strings.EqualFold(got, v.publicKey) {
+				t.Errorf("vector %d: khóa công khai %s", v.index, got)
+			}
+			sig, err := signSchnorrAux(d, message, mustHex(t, v.auxRand), nil)
+			if err != nil {
+				t.Fatalf("vector %d: %v", v.index, err)
+			}
+			if got := hex.EncodeToString(sig); !strings.EqualFold(got, v.signature) {
+				t.Errorf("vector %d: chữ ký %s", v.index, got)
+			}
+		}
+
+		valid, _ := verifySchnorr(publicKey, string(message), strings.ToLower(v.signature), nil)
+		if valid != v.valid {
+			t.Errorf("vector %d: xác thực trả về %v, cần %v", v.index, valid, v.valid)
+		}
+	}
+}
+
+func TestSchnorrSignTraceHidesSecrets(t *testing.T) {
+	d := new(big.Int).SetBytes(mustHex(t, bip340Vectors[1].secretKey))
+	aux := mustHex(t, bip340Vectors[1].auxRand)
+
+	tr := newTrace(true)
+	if _, err := signSchnorrAux(d, mustHex(t, bip340Vectors[1].message), aux, tr); err != nil {
+		t.Fatal(err)
+	}
+	for _, step := range tr.Steps() {
+		if len(step.Ladder) > 0 {
+			t.Errorf("trace ghi các bước nhân điểm %q", step.Name)
+		}
+		if strings.Contains(step.Value, d.Text(16)) {
+			t.Errorf("trace chứa khóa bí mật ở bước %q", step.Name)
+		}
+	}
+}
+
+func TestSchnorrBatchVerify(t *testing.T) {
+	var publicKeys [][]byte
+	var entries []SchnorrBatchEntry
+	for _, v := range bip340Vectors {
+		if v.valid {
+			publicKeys = append(publicKeys, mustHex(t, v.publicKey))
+			entries = append(entries, SchnorrBatchEntry{
+				Message:   string(mustHex(t, v.message)),
+				Signature: strings.ToLower(v.signature),
+			})
+		}
+	}
+
+	valid, err := verifySchnorrBatch(publicKeys, entries, nil)
+	if err != nil || !valid {
+		t.Fatalf("lô chữ ký hợp lệ bị từ chối: %v", err)
+	}
+
+	// Mỗi chữ ký sai trong lô đều làm cả lô không hợp lệ
+	for _, v := range bip340Vectors {
+		if v.valid {
+			continue
+		}
+		badKeys := append(append([][]byte{}, publicKeys...), mustHex(t, v.publicKey))
+		badEntries := append(append([]SchnorrBatchEntry{}, entries...), SchnorrBatchEntry{
+			Message:   string(mustHex(t, v.message)),
+			Signature: strings.ToLower(v.signature),
+		})
+		if valid, _ := verifySchnorrBatch(badKeys, badEntries, nil); valid {
+			t.Errorf("lô chứa vector %d sai vẫn hợp lệ", v.index)
+		}
+	}
+
+	// Chữ ký hợp lệ nhưng gắn với thông điệp khác
+	tampered := append([]SchnorrBatchEntry{}, entries...)
+	tampered[2].Message += "x"
+	if valid, _ := verifySchnorrBatch(publicKeys, tampered, nil); valid {
+		t.Error("lô có thông điệp bị sửa vẫn hợp lệ")
+	}
+}
+
+func TestSchnorrUsesSeparateKeyType(t *testing.T) {
+	reg := useTestRegistry(t)
+	if _, err := reg.generate(KeyTypeSecp256k1, "eth", KeyOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reg.generate(KeyTypeSchnorr, "schnorr", KeyOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Khóa Ethereum không ký Schnorr được
+	req := SignRequest{Algorithm: "SCHNORR", KeyID: "eth", Message: "hello"}
+	if code := serveJSON(t, "/sign", signHandler, http.MethodPost, "/sign", req, nil); code != http.StatusNotFound {
+		t.Fatalf("ký Schnorr bằng khóa SECP256K1: %d", code)
+	}
+	req = SignRequest{Algorithm: "SECP256K1", KeyID: "schnorr", Message: "hello"}
+	if code := serveJSON(t, "/sign", signHandler, http.MethodPost, "/sign", req, nil); code != http.StatusNotFound {
+		t.Fatalf("ký SECP256K1 bằng khóa Schnorr: %d", code)
+	}
+
+	var sig SignResponse
+	req = SignRequest{Algorithm: "SCHNORR", KeyID: "schnorr", Message: "hello"}
+	if code := serveJSON(t, "/sign", signHandler, http.MethodPost, "/sign", req, &sig); code != http.StatusOK {
+		t.Fatalf("ký Schnorr: %d", code)
+	}
+	if sig.Address != "" {
+		t.Fatalf("chữ ký Schnorr kèm địa chỉ Ethereum %s", sig.Address)
+	}
+	var verify VerifyResponse
+	vreq := VerifyRequest{Algorithm: "SCHNORR", KeyID: "schnorr", Message: "hello", Signature: sig.Signature}
+	serveJSON(t, "/verify", verifyHandler, http.MethodPost, "/verify", vreq, &verify)
+	if !verify.IsValid {
+		t.Fatal("chữ ký Schnorr không hợp lệ")
+	}
+
+	// Khóa Schnorr được lưu và đọc lại như các loại khóa khác
+	key, _ := reg.get("schnorr")
+	reloaded, err := reg.loadKey("schnorr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reloaded.Type != KeyTypeSchnorr || !reloaded.latest().Schnorr.Equal(key.latest().Schnorr) {
+		t.Fatal("khóa Schnorr khác sau khi đọc lại")
+	}
+}
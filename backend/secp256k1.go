@@ -0,0 +1,84 @@
+// secp256k1.go
+package main
+
+import (
+	"crypto/ecdsa"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"strings"
+
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+)
+
+// Hàm sinh khóa secp256k1
+func generateSecp256k1Key() (*ecdsa.PrivateKey, error) {
+	return ethcrypto.GenerateKey()
+}
+
+// Giá trị băm được ký: Keccak-256 của thông điệp như Ethereum
+func secp256k1Hash(message string) []byte {
+	return ethcrypto.Keccak256([]byte(message))
+}
+
+// Địa chỉ Ethereum (EIP-55) của khóa công khai
+func ethereumAddress(pub *ecdsa.PublicKey) string {
+	return ethcrypto.PubkeyToAddress(*pub).Hex()
+}
+
+// Ký giá trị băm, chữ ký khôi phục được 65 byte r || s || v (v = 27 hoặc 28) dạng hex có tiền tố 0x
+func signSecp256k1Hash(privateKey *ecdsa.PrivateKey, hash []byte) (string, error) {
+	signature, err := ethcrypto.Sign(hash, privateKey)
+	if err != nil {
+		return "", err
+	}
+	signature[64] += 27
+	return "0x" + hex.EncodeToString(signature), nil
+}
+
+// Hàm ký thông điệp bằng secp256k1
+func signSecp256k1(privateKey *ecdsa.PrivateKey, message string) (string, error) {
+	return signSecp256k1Hash(privateKey, secp256k1Hash(message))
+}
+
+// Đọc chữ ký 65 byte, chấp nhận v = 0/1 hoặc 27/28; trả về chữ ký với v = 0/1
+func decodeSecp256k1Signature(signature string) ([]byte, error) {
+	sig, err := hex.DecodeString(strings.TrimPrefix(signature, "0x"))
+	if err != nil || len(sig) != 65 {
+		return nil, errors.New("chữ ký secp256k1 phải gồm 65 byte dạng hex")
+	}
+	if sig[64] >= 27 {
+		sig[64] -= 27
+	}
+	if sig[64] > 1 {
+		return nil, errors.New("giá trị v của chữ ký không hợp lệ")
+	}
+	return sig, nil
+}
+
+// Khôi phục khóa công khai của người ký từ giá trị băm và chữ ký
+func recoverSecp256k1(hash []byte, signature string) (*ecdsa.PublicKey, error) {
+	sig, err := decodeSecp256k1Signature(signature)
+	if err != nil {
+		return nil, err
+	}
+	// Chỉ chấp nhận s thấp như Ethereum (EIP-2)
+	if !ethcrypto.ValidateSignatureValues(sig[64], new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:64]), true) {
+		return nil, errors.New("chữ ký secp256k1 không hợp lệ")
+	}
+	return ethcrypto.SigToPub(hash, sig)
+}
+
+// Xác thực giá trị băm: khóa khôi phục từ chữ ký phải trùng khóa công khai
+func verifySecp256k1Hash(publicKey *ecdsa.PublicKey, hash []byte, signature string) (bool, error) {
+	recovered, err := recoverSecp256k1(hash, signature)
+	if err != nil {
+		return false, err
+	}
+	return recovered.Equal(publicKey), nil
+}
+
+// Hàm xác thực chữ ký secp256k1
+func verifySecp256k1(publicKey *ecdsa.PublicKey, message, signature string) (bool, error) {
+	return verifySecp256k1Hash(publicKey, secp256k1Hash(message), signature)
+}
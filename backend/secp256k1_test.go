@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/hex"
+	"math/big"
+	"strings"
+	"testing"
+
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestSecp256k1RecoverReturnsSignerAddress(t *testing.T) {
+	priv, err := generateSecp256k1Key()
+	if err != nil {
+		t.Fatal(err)
+	}
+	message := "xin chào secp256k1"
+	signature, err := signSecp256k1(priv, message)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recovered, err := recoverSecp256k1(secp256k1Hash(message), signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ethereumAddress(recovered) != ethereumAddress(&priv.PublicKey) {
+		t.Fatalf("địa chỉ khôi phục %s, cần %s", ethereumAddress(recovered), ethereumAddress(&priv.PublicKey))
+	}
+	if ok, err := verifySecp256k1(&priv.PublicKey, message, signature); !ok || err != nil {
+		t.Fatalf("chữ ký hợp lệ bị từ chối: %v", err)
+	}
+	if ok, _ := verifySecp256k1(&priv.PublicKey, message+".", signature); ok {
+		t.Fatal("chấp nhận chữ ký cho thông điệp khác")
+	}
+
+	// v = 0/1 cũng được chấp nhận
+	sig, _ := hex.DecodeString(strings.TrimPrefix(signature, "0x"))
+	sig[64] -= 27
+	if ok, err := verifySecp256k1(&priv.PublicKey, message, hex.EncodeToString(sig)); !ok || err != nil {
+		t.Fatalf("chữ ký với v = %d bị từ chối: %v", sig[64], err)
+	}
+
+	// Chữ ký s cao (n - s, đảo v) bị từ chối theo EIP-2
+	s := new(big.Int).SetBytes(sig[32:64])
+	s.Sub(ethcrypto.S256().Params().N, s)
+	s.FillBytes(sig[32:64])
+	sig[64] ^= 1
+	if ok, _ := verifySecp256k1(&priv.PublicKey, message, hex.EncodeToString(sig)); ok {
+		t.Fatal("chấp nhận chữ ký s cao")
+	}
+}
+
+func TestEthereumAddressOfKnownKey(t *testing.T) {
+	// Khóa bí mật 1: khóa công khai là G
+	priv, err := ethcrypto.HexToECDSA("0000000000000000000000000000000000000000000000000000000000000001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := ethereumAddress(&priv.PublicKey), "0x7E5F4552091A69125d5DfCb7b8C2659029395Bdf"; got != want {
+		t.Fatalf("địa chỉ %s, cần %s", got, want)
+	}
+}
@@ -0,0 +1,111 @@
+// trace.go
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+)
+
+// Các thuật toán hỗ trợ chế độ trace (các thuật toán dùng thư viện chuẩn không có giá trị trung gian)
+var (
+	traceEncryptionAlgorithms = map[string]bool{"RSA": true, "ELGAMAL": true, "ECC": true, "EC-ELGAMAL": true}
+	traceSignatureAlgorithms  = map[string]bool{"RSA": true, "ELGAMAL": true, "EC-ECDSA": true, "SCHNORR": true, "DSA": true}
+)
+
+// Một giá trị trung gian; block là số thứ tự khối (từ 1) khi thông điệp được chia khối
+type TraceStep struct {
+	Block  int               `json:"block,omitempty"`
+	Name   string            `json:"name"`
+	Value  string            `json:"value"`
+	Ladder []TraceLadderStep `json:"ladder,omitempty"`
+}
+
+// Một bước double-and-add, điểm dạng "(x, y)" hex hoặc "O"
+type TraceLadderStep struct {
+	Bit   int    `json:"bit"`
+	Op    string `json:"op"`
+	Point string `json:"point"`
+}
+
+// Trace ghi lại các giá trị trung gian của thuật toán.
+// Không ghi giá trị bí mật: khóa bí mật, nonce k khi ký và các bước nhân điểm với khóa bí mật.
+// Mọi phương thức đều an toàn khi t == nil (chế độ trace tắt).
+type Trace struct {
+	block int
+	steps []TraceStep
+}
+
+func newTrace(enabled bool) *Trace {
+	if !enabled {
+		return nil
+	}
+	return &Trace{}
+}
+
+// Đặt số thứ tự khối cho các giá trị ghi sau đó
+func (t *Trace) setBlock(block int) {
+	if t != nil {
+		t.block = block
+	}
+}
+
+// Ghi một giá trị: số nguyên và byte được ghi dạng hex
+func (t *Trace) add(name string, value any) {
+	if t == nil {
+		return
+	}
+	t.steps = append(t.steps, TraceStep{Block: t.block, Name: name, Value: formatTraceValue(value)})
+}
+
+// Ghi một điểm trên đường cong
+func (t *Trace) addPoint(name string, x, y *big.Int) {
+	t.add(name, formatPoint(x, y))
+}
+
+// Nhân điểm k*(x, y), ghi lại kết quả và các bước double-and-add khi trace bật.
+// Các bước lộ từng bit của k nên chỉ dùng khi k công khai hoặc là k tạm thời khi mã hóa.
+func (t *Trace) pointMultiply(name string, c *Curve, k, x, y *big.Int) (*big.Int, *big.Int) {
+	if t == nil {
+		return pointMultiply(c, k, x, y)
+	}
+
+	var steps []LadderStep
+	rx, ry := pointMultiplyLadder(c, k, x, y, &steps)
+
+	step := TraceStep{Block: t.block, Name: name, Value: formatPoint(rx, ry)}
+	for _, s := range steps {
+		step.Ladder = append(step.Ladder, TraceLadderStep{Bit: s.Bit, Op: s.Op, Point: formatPoint(s.Result.X, s.Result.Y)})
+	}
+	t.steps = append(t.steps, step)
+	return rx, ry
+}
+
+// Các giá trị đã ghi, nil khi trace tắt
+func (t *Trace) Steps() []TraceStep {
+	if t == nil {
+		return nil
+	}
+	return t.steps
+}
+
+func formatTraceValue(value any) string {
+	switch v := value.(type) {
+	case *big.Int:
+		if v == nil {
+			return "không tồn tại"
+		}
+		return v.Text(16)
+	case []byte:
+		return hex.EncodeToString(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func formatPoint(x, y *big.Int) string {
+	if x == nil {
+		return "O"
+	}
+	return fmt.Sprintf("(%x, %x)", x, y)
+}
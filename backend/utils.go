@@ -2,10 +2,130 @@
 package main
 
 import (
-	"github.com/ethereum/go-ethereum/crypto/secp256k1"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sync"
+	"time"
 )
 
-func generatePrime(bits int) string {
-	prime, _ := secp256k1.S256().N.SetString("FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFEFFFFFC2F", 16)
-	return prime.String()
+// Số nguyên tố an toàn p = 2q + 1 với q cũng là số nguyên tố
+type safePrime struct {
+	P, Q *big.Int
+}
+
+// Các số nguyên tố lẻ nhỏ dùng để sàng trước khi kiểm tra Miller-Rabin
+var smallPrimes = func() []*big.Int {
+	var primes []*big.Int
+	for n := int64(3); n < 2000; n += 2 {
+		if big.NewInt(n).ProbablyPrime(0) {
+			primes = append(primes, big.NewInt(n))
+		}
+	}
+	return primes
+}()
+
+// Sinh số nguyên tố an toàn p có đúng bits bit
+func generateSafePrime(bits int) (*safePrime, error) {
+	if bits < 16 {
+		return nil, errors.New("số nguyên tố an toàn phải có ít nhất 16 bit")
+	}
+
+	one := big.NewInt(1)
+	rem := new(big.Int)
+	for {
+		// q ngẫu nhiên có bits-1 bit, hai bit cao nhất bằng 1 để p = 2q + 1 đủ bits bit
+		q, err := rand.Int(rand.Reader, new(big.Int).Lsh(one, uint(bits-1)))
+		if err != nil {
+			return nil, fmt.Errorf("lỗi khi tạo số ngẫu nhiên: %v", err)
+		}
+		q.SetBit(q, bits-2, 1)
+		q.SetBit(q, bits-3, 1)
+		q.SetBit(q, 0, 1)
+
+		// Sàng: loại q nếu q hoặc 2q + 1 chia hết cho một số nguyên tố nhỏ r,
+		// tức là q mod r bằng 0 hoặc (r - 1) / 2
+		candidate := true
+		for _, sp := range smallPrimes {
+			r := rem.Mod(q, sp).Uint64()
+			if r == 0 || r == (sp.Uint64()-1)/2 {
+				candidate = false
+				break
+			}
+		}
+		if !candidate || !q.ProbablyPrime(0) {
+			continue
+		}
+
+		p := new(big.Int).Lsh(q, 1)
+		p.Add(p, one)
+		if p.ProbablyPrime(20) && q.ProbablyPrime(20) {
+			return &safePrime{P: p, Q: q}, nil
+		}
+	}
+}
+
+// Kho số nguyên tố an toàn sinh sẵn ở nền, vì sinh số nguyên tố an toàn rất chậm
+var safePrimePools = struct {
+	sync.Mutex
+	pools map[int]chan *safePrime
+}{pools: map[int]chan *safePrime{}}
+
+// Bắt đầu sinh sẵn số nguyên tố an toàn bits bit, giữ tối đa size số trong kho
+func startSafePrimePool(bits, size int) {
+	safePrimePools.Lock()
+	defer safePrimePools.Unlock()
+
+	if _, ok := safePrimePools.pools[bits]; ok {
+		return
+	}
+	pool := make(chan *safePrime, size)
+	safePrimePools.pools[bits] = pool
+
+	go func() {
+		for {
+			sp, err := generateSafePrime(bits)
+			if err != nil {
+				fmt.Println("Error generating safe prime:", err)
+				time.Sleep(time.Second)
+				continue
+			}
+			pool <- sp
+		}
+	}()
+}
+
+// Lấy số nguyên tố an toàn bits bit: từ kho nếu có, nếu không thì sinh trực tiếp
+func takeSafePrime(bits int) (*safePrime, error) {
+	safePrimePools.Lock()
+	pool, ok := safePrimePools.pools[bits]
+	safePrimePools.Unlock()
+
+	if ok {
+		return <-pool, nil
+	}
+	return generateSafePrime(bits)
+}
+
+// Đọc biến môi trường, trả về giá trị mặc định nếu chưa đặt
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// Chuyển các chuỗi hex thành big.Int
+func parseHexInts(values ...string) ([]*big.Int, error) {
+	ints := make([]*big.Int, len(values))
+	for i, s := range values {
+		n, ok := new(big.Int).SetString(s, 16)
+		if !ok {
+			return nil, fmt.Errorf("giá trị hex không hợp lệ: %q", s)
+		}
+		ints[i] = n
+	}
+	return ints, nil
 }
@@ -57,8 +57,14 @@ function Home() {
           onChange={handleAlgorithmChange}
         >
           <option value="RSA">RSA</option>
+          <option value="RSA-AES-GCM">RSA-OAEP + AES-GCM</option>
           <option value="ECC">ECC</option>
+          <option value="ECIES">ECIES (ECDH + AES-GCM)</option>
+          <option value="EC-ELGAMAL">EC-ElGamal (Koblitz)</option>
           <option value="ElGamal">ElGamal</option>
+          <option value="AES-GCM">AES-GCM</option>
+          <option value="CHACHA20-POLY1305">ChaCha20-Poly1305</option>
+          <option value="XCHACHA20-POLY1305">XChaCha20-Poly1305</option>
         </select>
         <Link to="/sign">
           <button className="btn-tran">Signature</button>
@@ -3,12 +3,20 @@ import { Link } from "react-router-dom";
 import axios from "axios";
 import crypto from "crypto-js";
 
+// Các thuật toán ký có nonce k chọn được (ngẫu nhiên hoặc RFC 6979)
+const nonceAlgorithms = ["ECC", "EC-ECDSA", "DSA", "ElGamal"];
+
 function Sign() {
   const [input, setInput] = useState("");
   const [signature, setSignature] = useState("");
   const [hashInput, setHashInput] = useState("");
   const [verification, setVerification] = useState("");
   const [algorithm, setAlgorithm] = useState("RSA");
+  const [rsaScheme, setRsaScheme] = useState("pkcs1v15"); // Lược đồ chữ ký RSA
+  const [rsaHash, setRsaHash] = useState("SHA-256");
+  const [ecdsaFormat, setEcdsaFormat] = useState("legacy"); // Định dạng chữ ký ECDSA
+  const [elgamalScheme, setElgamalScheme] = useState("hashed"); // Lược đồ chữ ký ElGamal
+  const [nonce, setNonce] = useState("random"); // Cách chọn nonce k (ECDSA, DSA, ElGamal)
   const [hashedMessage, setHashedMessage] = useState(""); // Dùng để lưu hash của input bên người gửi
   const [hashedHashInput, setHashedHashInput] = useState(""); // Dùng để lưu hash của input bên người nhận
 
@@ -22,6 +30,10 @@ function Sign() {
       const response = await axios.post("http://localhost:8080/sign", {
         message: hash,
         algorithm: algorithm,
+        ...(algorithm === "RSA" && { scheme: rsaScheme, hash: rsaHash }),
+        ...(algorithm === "ECC" && { format: ecdsaFormat }),
+        ...(algorithm === "ElGamal" && { scheme: elgamalScheme }),
+        ...(nonceAlgorithms.includes(algorithm) && { nonce: nonce }),
       });
 
       setSignature(response.data.signature || "Lỗi khi tạo chữ ký");
@@ -63,8 +75,47 @@ function Sign() {
         >
           <option value="RSA">RSA</option>
           <option value="ECC">ECC</option>
+          <option value="EC-ECDSA">ECDSA (tự cài đặt)</option>
+          <option value="Ed25519">Ed25519</option>
+          <option value="SECP256K1">secp256k1 (Ethereum)</option>
+          <option value="EIP191">Ethereum personal_sign (EIP-191)</option>
+          <option value="SCHNORR">Schnorr BIP-340 (secp256k1)</option>
           <option value="ElGamal">ElGamal</option>
+          <option value="DSA">DSA (FIPS 186)</option>
         </select>
+        {algorithm === "RSA" && (
+          <>
+            <select value={rsaScheme} onChange={(e) => setRsaScheme(e.target.value)}>
+              <option value="pkcs1v15">PKCS#1 v1.5</option>
+              <option value="pss">PSS</option>
+            </select>
+            <select value={rsaHash} onChange={(e) => setRsaHash(e.target.value)}>
+              <option value="SHA-256">SHA-256</option>
+              <option value="SHA-384">SHA-384</option>
+              <option value="SHA-512">SHA-512</option>
+            </select>
+          </>
+        )}
+        {algorithm === "ECC" && (
+          <select value={ecdsaFormat} onChange={(e) => setEcdsaFormat(e.target.value)}>
+            <option value="legacy">r|s (hex)</option>
+            <option value="der">DER</option>
+            <option value="p1363">P1363</option>
+          </select>
+        )}
+        {algorithm === "ElGamal" && (
+          <select value={elgamalScheme} onChange={(e) => setElgamalScheme(e.target.value)}>
+            <option value="hashed">Hashed (SHA-256)</option>
+            <option value="subgroup">Nhóm con (kiểu DSA)</option>
+            <option value="textbook">Textbook (không an toàn)</option>
+          </select>
+        )}
+        {nonceAlgorithms.includes(algorithm) && (
+          <select value={nonce} onChange={(e) => setNonce(e.target.value)}>
+            <option value="random">Nonce ngẫu nhiên</option>
+            <option value="rfc6979">Nonce RFC 6979</option>
+          </select>
+        )}
         <Link to="/">
           <button className="btn-tran">Encrypt</button>
         </Link>